@@ -0,0 +1,147 @@
+// Package cohort builds aggregate performance tables from the table built by package collapse.
+//
+// There is one row per vintage per period, where the period is either loan age or calendar month.  The rows may be
+// further grouped by any of ByFields.  The fields are:
+//   - loans.    # of loans reporting in the period.
+//   - upb.      Total upb.
+//   - dq30, dq60, dq90, dq180.  # of loans 1, 2, 3-5 and 6+ months delinquent.
+//   - cpr.      Conditional prepayment rate from the smm weighted by the prior month's upb.
+//   - cdr.      Conditional default rate from the upb of liquidated loans.
+//   - loss.     Net loss of the loans liquidated in the period.
+//   - cumLoss.  Cumulative net loss through the period as a fraction of the cohort's original balance.
+//
+// The table is rebuilt each time.  Materialized views aren't used since the cumulative loss spans the inserts.
+package cohort
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	s "github.com/invertedv/chutils/sql"
+	"github.com/invertedv/fannie/raw"
+	"strings"
+)
+
+// ByFields are the fields the cohorts may be grouped by in addition to vintage
+var ByFields = []string{"state", "channel", "purpose", "standard", "harp"}
+
+// Build builds table from sourceTable, the table built by package collapse.  by are fields from ByFields to group
+// by.  period is "age" or "month".
+func Build(sourceTable string, table string, by []string, period string, con *chutils.Connect) error {
+	if period != "age" && period != "month" {
+		return fmt.Errorf("period must be age or month, got %s", period)
+	}
+	for _, b := range by {
+		if !legalBy(b) {
+			return fmt.Errorf("cannot group cohorts by %s, fields are: %s", b, strings.Join(ByFields, ", "))
+		}
+	}
+	grp := strings.Join(append([]string{"vintage"}, by...), ", ")
+	// the monthly arrays are joined as m<Field>
+	q := strings.Replace(strings.Replace(strings.Replace(qry, "sourceTable", sourceTable, 2), "<by>", grp, -1),
+		"<period>", "m"+strings.ToUpper(period[:1])+period[1:], -1)
+
+	rdr := s.NewReader(q, con)
+	defer func() { _ = rdr.Close() }()
+	if e := rdr.Init(grp+", period", chutils.MergeTree); e != nil {
+		return e
+	}
+	for _, f := range rdr.TableSpec().FieldDefs {
+		raw.CopySpec(f)
+		switch f.Name {
+		case "harp":
+			f.Description = "loan is HARP: Y, N"
+			f.ChSpec.Base = chutils.ChFixedString
+			f.ChSpec.Length = 1
+		case "period":
+			f.Description = "loan " + period
+		case "loans":
+			f.Description = "# of loans reporting"
+		case "upb":
+			f.Description = "total upb"
+		case "dq30":
+			f.Description = "# of loans 1 month delinquent"
+		case "dq60":
+			f.Description = "# of loans 2 months delinquent"
+		case "dq90":
+			f.Description = "# of loans 3-5 months delinquent"
+		case "dq180":
+			f.Description = "# of loans 6+ months delinquent"
+		case "cpr":
+			f.Description = "conditional prepayment rate: 1 - (1 - smm)^12, smm weighted by prior upb"
+		case "cdr":
+			f.Description = "conditional default rate: 1 - (1 - mdr)^12, mdr = prior upb of liquidated loans / prior upb"
+		case "loss":
+			f.Description = "net loss of loans liquidated in the period"
+		case "cumLoss":
+			f.Description = "cumulative net loss / original balance of the cohort"
+		}
+	}
+	if e := rdr.TableSpec().Create(con, table); e != nil {
+		return e
+	}
+	rdr.Name = table
+	rdr.Sql = strings.Replace(rdr.Sql, "LIMIT 10", "", 2)
+	return rdr.Insert()
+}
+
+// legalBy returns true if field is in ByFields
+func legalBy(field string) bool {
+	for _, b := range ByFields {
+		if b == field {
+			return true
+		}
+	}
+	return false
+}
+
+// qry builds the cohort table.  There are placeholders for the source table, the grouping fields <by> and the
+// period field.
+//
+// Note: the "LIMIT 10" statements make the query run much faster for the Init() method.
+const qry = `
+WITH c AS (
+  SELECT
+    <by>,
+    toFloat64(sum(opb)) AS cohortOpb
+  FROM (SELECT * FROM sourceTable LIMIT 10)
+  GROUP BY <by>),
+p AS (
+  SELECT
+    <by>,
+    <period> AS period,
+    toInt64(count(*)) AS loans,
+    toFloat64(sumIf(mUpb, mUpb > 0)) AS upb,
+    toInt64(countIf(mDq = 1)) AS dq30,
+    toInt64(countIf(mDq = 2)) AS dq60,
+    toInt64(countIf(mDq >= 3 AND mDq < 6)) AS dq90,
+    toInt64(countIf(mDq >= 6)) AS dq180,
+    sumIf(mSmm * mUpbPrior, mSmm >= 0 AND mUpbPrior > 0) / sumIf(mUpbPrior, mSmm >= 0 AND mUpbPrior > 0) AS smmAgg,
+    sumIf(mUpbPrior, has(['02', '03', '09', '15'], mZb) AND mUpbPrior > 0) / sumIf(mUpbPrior, mUpbPrior > 0) AS mdr,
+    toFloat64(sumIf(netLoss, has(['02', '03', '09', '15'], mZb))) AS loss
+  FROM (SELECT * FROM sourceTable LIMIT 10)
+  ARRAY JOIN
+    monthly.month AS mMonth,
+    monthly.age AS mAge,
+    monthly.upb AS mUpb,
+    arrayPushFront(arrayPopBack(monthly.upb), -1) AS mUpbPrior,
+    monthly.dq AS mDq,
+    monthly.smm AS mSmm,
+    monthly.zb AS mZb
+  GROUP BY <by>, period)
+SELECT
+  <by>,
+  period,
+  loans,
+  upb,
+  dq30,
+  dq60,
+  dq90,
+  dq180,
+  toFloat64(isFinite(smmAgg) ? 1 - pow(1 - smmAgg, 12) : 0) AS cpr,
+  toFloat64(isFinite(mdr) ? 1 - pow(1 - mdr, 12) : 0) AS cdr,
+  loss,
+  toFloat64(cohortOpb > 0 ? sum(loss) OVER (PARTITION BY <by> ORDER BY period) / cohortOpb : 0) AS cumLoss
+FROM p
+JOIN c
+USING (<by>)
+`
@@ -1,8 +1,13 @@
 // Package collapse reduces the raw table that has one entry per loan per month to a table that has one entry
 // per loan.
 // There are nested tables:
-//   - monthly.  These are values that change every month.
-//   - qa. The qa table.
+//   - monthly.  These are values that change every month.  These include the prepayment measures schedPrin, curtail
+//     and smm, which are calculated from the monthly upb.  With raw.Full, the monthly fields the lean schema
+//     drops (e.g. ficoCur, curListPrice) are included.
+//   - payHist. The 24-month pay history decoded to months delinquent by calendar month.  The history covers the
+//     24 months before the month of the row that reports it.
+//   - dqHist. The monthly dq back-filled from payHist for the months before the loan's first month.
+//   - qa. The qa table.  This includes the longitudinal checks of the monthly history, which are named ts:<check>.
 package collapse
 
 import (
@@ -13,11 +18,27 @@ import (
 	"strings"
 )
 
+// Buckets is the # of values of the bucket field, which is calculated from the bits of lnId.
+var Buckets = 20
+
 // GroupBy groups the raw table (which has one row per loan per month to a table with one row per loan
 func GroupBy(sourceTable string, table string, harpTable string, create bool, con *chutils.Connect) error {
+	// without a HARP map, join to an empty one
+	if harpTable == "" {
+		harpTable = noHarp
+	}
 	// remove placeholder table names
 	q := strings.Replace(strings.Replace(qry, "sourceTable", sourceTable, 2), "harpTable", harpTable, 2)
 
+	q = strings.Replace(q, "<buckets>", fmt.Sprintf("%d", Buckets), 1)
+
+	// the query has placeholders for the fields loaded by raw.Full
+	monthly, static := "", ""
+	if raw.Full {
+		monthly, static = fullMonthly, fullStatic
+	}
+	q = strings.Replace(strings.Replace(q, "<fullMonthly>", monthly, 1), "<fullStatic>", static, 1)
+
 	// the query has placeholders for the missing values of fields of the form <fieldMissing>
 	for _, f := range raw.TableDef.FieldDefs {
 		q = strings.Replace(q, fmt.Sprintf("<%sMissing>", f.Name), fmt.Sprintf("%v", f.Missing), -1)
@@ -33,6 +54,12 @@ func GroupBy(sourceTable string, table string, harpTable string, create bool, co
 		if e := rdr.TableSpec().Nest("monthly", "month", "matDt"); e != nil {
 			return e
 		}
+		if e := rdr.TableSpec().Nest("payHist", "phMonth", "phDq"); e != nil {
+			return e
+		}
+		if e := rdr.TableSpec().Nest("dqHist", "dhMonth", "dhDq"); e != nil {
+			return e
+		}
 		if e := rdr.TableSpec().Nest("qa", "field", "cntFail"); e != nil {
 			return e
 		}
@@ -54,7 +81,7 @@ func GroupBy(sourceTable string, table string, harpTable string, create bool, co
 			// added details for new fields
 			switch f.Name {
 			case "bucket":
-				f.Description = "loan bucket"
+				f.Description = fmt.Sprintf("loan bucket: 0-%d", Buckets-1)
 			case "ageFpDt":
 				f.Description = "age based on fdDt, missing=-1000"
 			case "harpLnId":
@@ -65,9 +92,53 @@ func GroupBy(sourceTable string, table string, harpTable string, create bool, co
 				f.Description = "loan is HARP: Y, N"
 				f.ChSpec.Base = chutils.ChFixedString
 				f.ChSpec.Length = 1
+			case "phMonth":
+				f.Description = "month of pay history"
+			case "phDq":
+				f.Description = "months delinquent from pay history, missing=-1"
+			case "dhMonth":
+				f.Description = "month of dq history"
+			case "dhDq":
+				f.Description = "months delinquent: monthly dq back-filled from pay history before first month"
 			case "field":
-				f.Description = "field name"
+				f.Description = "field name, ts:<check> for longitudinal checks"
 				f.ChSpec.Funcs = append(f.ChSpec.Funcs, chutils.OuterLowCardinality)
+			case "schedPrin":
+				f.Description = "scheduled principal: prior upb * i / ((1 + i)^(rTermLgl + 1) - 1), i = curRate / 1200, missing=-1"
+			case "curtail":
+				f.Description = "curtailment: prior upb - upb - schedPrin, floored at 0, missing=-1"
+			case "smm":
+				f.Description = "single monthly mortality: (prior upb - upb - schedPrin) / (prior upb - schedPrin), " +
+					"1=paid off, 0=other zero balance, missing=-1. CPR = 1 - (1 - smm)^12"
+			case "curtailTotal":
+				f.Description = "total of curtail"
+			case "dqAccrInt":
+				f.Description = "delinquent accrued interest: months from lpDt to zbDt * (last curRate - " + servFee +
+					") / 1200 * zbUpb, 0=not liquidated"
+			case "totExp":
+				f.Description = "total expenses: fclExp + fclPExp + fclLExp + fclMExp + fclTaxes, 0=not liquidated"
+			case "totPro":
+				f.Description = "total proceeds: fclProNet + fclProMi + fclProMw + fclProOth, 0=not liquidated"
+			case "netLoss":
+				f.Description = "net loss: zbUpb + dqAccrInt + totExp - totPro, 0=not liquidated"
+			case "severity":
+				f.Description = "loss severity: netLoss / zbUpb, 0=not liquidated"
+			case "dq30Dt", "dq60Dt", "dq90Dt", "dq180Dt":
+				f.Description = fmt.Sprintf("first month %s+ days delinquent, missing=1970/1/1",
+					strings.TrimSuffix(strings.TrimPrefix(f.Name, "dq"), "Dt"))
+			case "modDt":
+				f.Description = "first month modified (mod=Y), missing=1970/1/1"
+			case "fbDt":
+				f.Description = "first month in forbearance (bap=F), missing=1970/1/1"
+			case "zbMonth":
+				f.Description = "first month with a zero balance code, missing=1970/1/1"
+			case "zbCode":
+				f.Description = "zero balance code of zbMonth (see zb), 00=not zero balance"
+				f.ChSpec.Base = chutils.ChFixedString
+				f.ChSpec.Length = 2
+			case "dq30ToLiq", "dq60ToLiq", "dq90ToLiq", "dq180ToLiq", "modToLiq", "fbToLiq":
+				f.Description = fmt.Sprintf("months from %sDt to zbMonth for liquidated loans, missing=-1",
+					strings.TrimSuffix(f.Name, "ToLiq"))
 			case "cntFail":
 				f.Description = "# of months field failed qa"
 			case "allFail":
@@ -87,11 +158,73 @@ func GroupBy(sourceTable string, table string, harpTable string, create bool, co
 	return nil
 }
 
+// tsNames are the names of the longitudinal checks, in the order of tsCnt in qry:
+//   - upbUp.    upb increases without a modification.
+//   - dqJump.   dq increases by more than one in a month.
+//   - ageStep.  age does not advance by the # of months elapsed.
+//   - matDtChg. matDt changes without a modification.
+//   - afterZb.  rows after the zero-balance month.
+//
+// The count is the # of months that fail the check.
+const tsNames = "['ts:upbUp', 'ts:dqJump', 'ts:ageStep', 'ts:matDtChg', 'ts:afterZb']"
+
+// noHarp is an empty map of non-HARP loans to HARP loans
+const noHarp = "(SELECT '' AS oldLnId, '' AS harpLnId WHERE 0)"
+
+// liquidated is true if the loan was liquidated: third party sale (02), short sale (03), REO disposition (09) or
+// note sale (15).  The loss fields are 0 for other loans.
+const liquidated = "(has(['02', '03', '09', '15'], r.zb[-1]) AND year(r.zbDt) > 1970)"
+
+// servFee is the servicing fee, in percent, that Fannie deducts from curRate in calculating delinquent accrued interest
+const servFee = "0.35"
+
+// fullMonthly are the monthly fields loaded by raw.Full.  They go before matDt, which ends the monthly nested table.
+const fullMonthly = `groupArray(lower(mServicer)) AS mServicer,
+  groupArray(miCancel) AS miCancel,
+  groupArray(schedPrinRpt) AS schedPrinRpt,
+  groupArray(unschedPrinRpt) AS unschedPrinRpt,
+  groupArray(curListDt) AS curListDt,
+  groupArray(curListPrice) AS curListPrice,
+  groupArray(ficoCur) AS ficoCur,
+  groupArray(coFicoCur) AS coFicoCur,
+  groupArray(modLoss) AS modLoss,
+  groupArray(ceLoss) AS ceLoss,
+  groupArray(holdback) AS holdback,
+  groupArray(armNextAdjDt) AS armNextAdjDt,
+  groupArray(armNextPayDt) AS armNextPayDt,`
+
+// fullStatic are the static fields loaded by raw.Full
+const fullStatic = `arrayMax(groupArray(upbIssue)) AS upbIssue,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(reprchDt)) AS reprchDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(origListDt)) AS origListDt,
+  arrayMax(groupArray(origListPrice)) AS origListPrice,
+  toInt32(arrayAvg(arrayFilter(x -> x != <ficoIssueMissing> ? 1 : 0, groupArray(ficoIssue))) > 0 ? arrayAvg(arrayFilter(x -> x != <ficoIssueMissing> ? 1 : 0, groupArray(ficoIssue))) : <ficoIssueMissing>)  AS ficoIssue,
+  toInt32(arrayAvg(arrayFilter(x -> x != <coFicoIssueMissing> ? 1 : 0, groupArray(coFicoIssue))) > 0 ? arrayAvg(arrayFilter(x -> x != <coFicoIssueMissing> ? 1 : 0, groupArray(coFicoIssue))) : <coFicoIssueMissing>)  AS coFicoIssue,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(zbChgDt)) AS zbChgDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(holdbackDt)) AS holdbackDt,
+  arrayMax(groupArray(dqIntRpt)) AS dqIntRpt,
+  arrayFirst(x->x!='<armLe5Missing>' AND x!='7' AND x!='9' ? 1 : 0, groupArray(armLe5)) = '' ? '<armLe5Missing>' : arrayFirst(x->x!='<armLe5Missing>' AND x!='7' AND x!='9' ? 1 : 0, groupArray(armLe5)) AS armLe5,
+  arrayFirst(x->x!='<armProductMissing>' ? 1 : 0, groupArray(armProduct)) = '' ? '<armProductMissing>' : arrayFirst(x->x!='<armProductMissing>' ? 1 : 0, groupArray(armProduct)) AS armProduct,
+  arrayMax(groupArray(armInitPer)) AS armInitPer,
+  arrayMax(groupArray(armAdjFreq)) AS armAdjFreq,
+  arrayFirst(x->x!='<armIndexMissing>' ? 1 : 0, groupArray(armIndex)) = '' ? '<armIndexMissing>' : arrayFirst(x->x!='<armIndexMissing>' ? 1 : 0, groupArray(armIndex)) AS armIndex,
+  arrayFirst(x->x!='<armCapStructMissing>' ? 1 : 0, groupArray(armCapStruct)) = '' ? '<armCapStructMissing>' : arrayFirst(x->x!='<armCapStructMissing>' ? 1 : 0, groupArray(armCapStruct)) AS armCapStruct,
+  arrayMax(groupArray(armInitCap)) AS armInitCap,
+  arrayMax(groupArray(armPerCap)) AS armPerCap,
+  arrayMax(groupArray(armLifeCap)) AS armLifeCap,
+  arrayMax(groupArray(armMargin)) AS armMargin,
+  indexOf(groupArray(balloon), 'Y') > 0 ? 'Y' : 'N' AS balloon,
+  arrayFirst(x->x!='<planNumMissing>' ? 1 : 0, groupArray(planNum)) = '' ? '<planNumMissing>' : arrayFirst(x->x!='<planNumMissing>' ? 1 : 0, groupArray(planNum)) AS planNum,`
+
 // qry is the query that collapses the multiple rows per lnId to a single one
 //
 // Note: there are two "LIMIT 10" statements.  These make the query to run much faster for the Init() method.
 // The Init() method appends a "LIMIT 1", but this query is complex enough that isn't helpful.
-// There are placeholders for the table created by package raw and the map of pre-HARP ids to HARP ids
+// There are placeholders for the table created by package raw and the map of pre-HARP ids to HARP ids,
+// for the fields loaded by raw.Full and for the # of buckets.
+// The qa field is split on colons, except the cross-field rules which have the form xf:<rule name>.
+// ClickHouse substitutes an alias wherever it is used, so expressions over the monthly arrays of r use the aliases
+// (upb, zb, ...) rather than groupArray, which would nest one aggregate inside another.
 const qry = `
 WITH q AS (
   SELECT lnId, 
@@ -100,7 +233,7 @@ WITH q AS (
 FROM (
   SELECT 
      lnId, 
-     arrayJoin(splitByChar(':', qa)) AS grp,
+     arrayJoin(extractAll(qa, 'xf:[^:]+|[^:]+')) AS grp,
      toInt32(count(*)) AS n
   FROM sourceTable 
   WHERE grp != ''
@@ -128,6 +261,17 @@ SELECT
   groupArray(frgvUpb) AS frgvUpb,
   groupArray(totPrin) AS totPrin,
   groupArray(servAct) AS servAct,
+  arrayMap((b, rt, n) -> b > 0 AND rt > 0 AND n >= 0 ? toFloat32(b * (rt / 1200) / (pow(1 + rt / 1200, n + 1) - 1)) : toFloat32(-1),
+    upbPrior, curRate, rTermLgl) AS schedPrin,
+  arrayMap((b, u, sp) -> sp >= 0 AND u > 0 ? toFloat32(greatest(b - u - sp, 0)) : toFloat32(-1),
+    upbPrior, upb, schedPrin) AS curtail,
+  arrayMap((b, u, sp, z) -> z = '01' ? toFloat32(1) : (z != '00' AND z != '' ? toFloat32(0) :
+    (sp >= 0 AND u > 0 AND b > sp ? toFloat32(least(greatest((b - u - sp) / (b - sp), 0), 1)) : toFloat32(-1))),
+    upbPrior, upb, schedPrin, zb) AS smm,
+  groupArray(hpiVal) AS hpiVal,
+  groupArray(eLtv) AS eLtv,
+  groupArray(incentive) AS incentive,
+  <fullMonthly>
   groupArray(matDt) AS matDt,
 
   arrayFirst(x->x!='<channelMissing>' ? 1 : 0, groupArray(channel)) = '' ? '<channelMissing>' : arrayFirst(x->x!='<channelMissing>' ? 1 : 0, groupArray(channel)) AS channel,
@@ -152,6 +296,7 @@ SELECT
   arrayFirst(x->x!='<occMissing>' ? 1 : 0, groupArray(occ)) = '' ? '<occMissing>' : arrayFirst(x->x!= '<occMissing>' ? 1 : 0, groupArray(occ)) AS occ,
   arrayFirst(x->x!='<stateMissing>' ? 1 : 0, groupArray(state)) = '' ? '<stateMissing>' : arrayFirst(x->x!='<stateMissing>' ? 1 : 0, groupArray(state)) AS state,
   arrayFirst(x->x!='<msaMissing>' ? 1 : 0, groupArray(msa)) = '' ? '<msaMissing>' : arrayFirst(x->x!='<msaMissing>' ? 1 : 0, groupArray(msa)) AS msa,
+  arrayFirst(x->x!='<msaOrigMissing>' ? 1 : 0, groupArray(msaOrig)) = '' ? '<msaOrigMissing>' : arrayFirst(x->x!='<msaOrigMissing>' ? 1 : 0, groupArray(msaOrig)) AS msaOrig,
   arrayFirst(x->x!='<zip3Missing>' ? 1 : 0, groupArray(zip3)) = '' ? '<zip3Missing>' : arrayFirst(x->x!='<zip3Missing>' ? 1 : 0, groupArray(zip3)) AS zip3,
   toFloat32(arrayAvg(arrayFilter(x -> x != <miMissing> ? 1 : 0, groupArray(mi))) > 0 ? arrayAvg(arrayFilter(x -> x != <miMissing> ? 1 : 0, groupArray(mi))) : <miMissing>)  AS mi,
   arrayFirst(x->x!='<amTypeMissing>' ? 1 : 0, groupArray(amType)) = '' ? '<amTypeMissing>' : arrayFirst(x->x!='<amTypeMissing>' ? 1 : 0, groupArray(amType)) AS amType,
@@ -188,13 +333,34 @@ SELECT
   arrayElement(groupArray(file), 1) AS file,
   arrayFirst(x->x!='<vintageMissing>' ? 1 : 0, groupArray(vintage)) = '' ? '<vintageMissing>' : arrayFirst(x->x!='<vintageMissing>' ? 1 : 0, groupArray(vintage)) AS vintage,
   toFloat32(arrayAvg(arrayFilter(x -> x != <propValMissing> ? 1 : 0, groupArray(propVal))) > 0 ? arrayAvg(arrayFilter(x -> x != <propValMissing> ? 1 : 0, groupArray(propVal))) : <propValMissing>)  AS propVal,
+  arrayElement(arrayConcat(arrayFilter(x -> x != <satoMissing>, groupArray(sato)), [toFloat32(<satoMissing>)]), 1) AS sato,
   position(lower(file), 'harp') > 0 ? 'Y' : 'N' AS harp,
   arrayElement(groupArray(standard), 1) AS standard,
+  arrayElement(groupArray(agency), 1) AS agency,
 
   arrayElement(groupArray(nsDoc), 1) AS nsDoc,
   arrayElement(groupArray(nsUw), 1) AS nsUw,
   arrayElement(groupArray(gGuar), 1) AS gGuar,
-  arrayElement(groupArray(negAm), 1) AS negAm
+  arrayElement(groupArray(negAm), 1) AS negAm,
+
+  arrayElement(groupArray(refPool), 1) AS refPool,
+  arrayElement(groupArray(dealName), 1) AS dealName,
+  arrayElement(groupArray(modLossCum), -1) AS modLossCum,
+  arrayElement(groupArray(ceLossCum), -1) AS ceLossCum,
+  <fullStatic>
+
+  arrayPushFront(arrayPopBack(upb), -1) AS upbPrior,
+
+  [toInt32(arrayCount((u, p, m) -> p > 0 AND u > p + 1 AND m != 'Y', arrayPopFront(upb), arrayPopBack(upb), arrayPopFront(mod))),
+   toInt32(arrayCount((d, p) -> p >= 0 AND d > p + 1, arrayPopFront(dq), arrayPopBack(dq))),
+   toInt32(arrayCount((a, p, m, pm) -> a >= 0 AND p >= 0 AND a - p != dateDiff('month', pm, m),
+     arrayPopFront(age), arrayPopBack(age), arrayPopFront(month), arrayPopBack(month))),
+   toInt32(arrayCount((d, p, m) -> year(d) > 1970 AND year(p) > 1970 AND d != p AND m != 'Y',
+     arrayPopFront(matDt), arrayPopBack(matDt), arrayPopFront(mod))),
+   toInt32(arrayCount(x -> x > 0, arrayCumSum(arrayMap(z -> z != '00' AND z != '' ? 1 : 0, arrayPopBack(zb)))))] AS tsCnt,
+
+  arrayElement(arrayFilter(p -> length(p) = 48, groupArray(payHist)), 1) AS phStr,
+  arrayElement(arrayFilter((m, p) -> length(p) = 48, month, groupArray(payHist)), 1) AS phAsOf
 FROM 
   (SELECT 
     *,
@@ -205,13 +371,43 @@ FROM
   LIMIT 10)
 GROUP BY lnId)
 select
-  r.*,
-  toInt32(modulo(arraySum(bitPositionsToArray(reinterpretAsUInt64(substr(r.lnId, 5, 8)))), 20)) AS bucket,
+  r.* EXCEPT (upbPrior, tsCnt, phStr, phAsOf),
+  toFloat32(` + liquidated + ` AND year(r.lpDt) > 1970 ?
+    greatest(dateDiff('month', r.lpDt, r.zbDt), 0) * greatest(arrayElement(arrayFilter(x -> x > 0, r.curRate), -1) - ` + servFee + `, 0) / 1200 * greatest(r.zbUpb, 0) : 0) AS dqAccrInt,
+  toFloat32(` + liquidated + ` ?
+    (r.fclExp = <fclExpMissing> ? 0 : r.fclExp) + (r.fclPExp = <fclPExpMissing> ? 0 : r.fclPExp) + (r.fclLExp = <fclLExpMissing> ? 0 : r.fclLExp) +
+    (r.fclMExp = <fclMExpMissing> ? 0 : r.fclMExp) + (r.fclTaxes = <fclTaxesMissing> ? 0 : r.fclTaxes) : 0) AS totExp,
+  toFloat32(` + liquidated + ` ?
+    (r.fclProNet = <fclProNetMissing> ? 0 : r.fclProNet) + (r.fclProMi = <fclProMiMissing> ? 0 : r.fclProMi) +
+    (r.fclProMw = <fclProMwMissing> ? 0 : r.fclProMw) + (r.fclProOth = <fclProOthMissing> ? 0 : r.fclProOth) : 0) AS totPro,
+  toFloat32(` + liquidated + ` ? greatest(r.zbUpb, 0) + dqAccrInt + totExp - totPro : 0) AS netLoss,
+  toFloat32(` + liquidated + ` AND r.zbUpb > 0 ? netLoss / r.zbUpb : 0) AS severity,
+  arrayFirst((m, d) -> d >= 1, r.month, r.dq) AS dq30Dt,
+  arrayFirst((m, d) -> d >= 2, r.month, r.dq) AS dq60Dt,
+  arrayFirst((m, d) -> d >= 3, r.month, r.dq) AS dq90Dt,
+  arrayFirst((m, d) -> d >= 6, r.month, r.dq) AS dq180Dt,
+  arrayFirst((m, x) -> x = 'Y', r.month, r.mod) AS modDt,
+  arrayFirst((m, x) -> x = 'F', r.month, r.bap) AS fbDt,
+  arrayFirst((m, z) -> z != '00' AND z != '', r.month, r.zb) AS zbMonth,
+  arrayFirst(z -> z != '00' AND z != '', r.zb) = '' ? '00' : arrayFirst(z -> z != '00' AND z != '', r.zb) AS zbCode,
+  toInt32(` + liquidated + ` AND year(dq30Dt) > 1970 ? dateDiff('month', dq30Dt, zbMonth) : -1) AS dq30ToLiq,
+  toInt32(` + liquidated + ` AND year(dq60Dt) > 1970 ? dateDiff('month', dq60Dt, zbMonth) : -1) AS dq60ToLiq,
+  toInt32(` + liquidated + ` AND year(dq90Dt) > 1970 ? dateDiff('month', dq90Dt, zbMonth) : -1) AS dq90ToLiq,
+  toInt32(` + liquidated + ` AND year(dq180Dt) > 1970 ? dateDiff('month', dq180Dt, zbMonth) : -1) AS dq180ToLiq,
+  toInt32(` + liquidated + ` AND year(modDt) > 1970 ? dateDiff('month', modDt, zbMonth) : -1) AS modToLiq,
+  toInt32(` + liquidated + ` AND year(fbDt) > 1970 ? dateDiff('month', fbDt, zbMonth) : -1) AS fbToLiq,
+  toFloat32(arraySum(arrayFilter(x -> x > 0, r.curtail))) AS curtailTotal,
+  toInt32(modulo(arraySum(bitPositionsToArray(reinterpretAsUInt64(substr(r.lnId, 5, 8)))), <buckets>)) AS bucket,
   v.harpLnId,
   x.oldLnId AS preHarpId,
-  q.qa AS field,
-  q.nqa AS cntFail,
-  arrayFilter((x,y) -> y=length(month) ? 1 : 0, qa, nqa) AS allFail
+  arrayMap(i -> toLastDayOfMonth(addMonths(r.phAsOf, i - 25)), range(1, length(r.phStr) = 48 ? 25 : 1)) AS phMonth,
+  arrayMap(i -> ifNull(toInt32OrNull(substring(r.phStr, 2 * i - 1, 2)), -1), range(1, length(r.phStr) = 48 ? 25 : 1)) AS phDq,
+  arrayConcat(arrayFilter(m -> m < month[1], phMonth), month) AS dhMonth,
+  arrayConcat(arrayFilter((d, m) -> m < month[1], phDq, phMonth), dq) AS dhDq,
+  arrayConcat(q.qa, arrayFilter((x, y) -> y > 0, ` + tsNames + `, r.tsCnt)) AS field,
+  arrayConcat(q.nqa, arrayFilter(y -> y > 0, r.tsCnt)) AS cntFail,
+  arrayConcat(arrayFilter((x,y) -> y=length(month) ? 1 : 0, q.qa, q.nqa),
+    arrayFilter((x, y) -> y > 0 AND y = length(month) - 1, ` + tsNames + `, r.tsCnt)) AS allFail
 FROM
   r 
 LEFT JOIN
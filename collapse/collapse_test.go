@@ -48,11 +48,17 @@ func ExampleGroupBy() {
 	//monthly.zb           Array(FixedString(2))           zero balance:00(noop), 01(pp), 03(short), 06(repurch), 09(REO), 96/97/98(removal), 02/15/16(sale), missing=X
 	//monthly.ioRem        Array(Int32)                    number of IO months remaining, missing=-1
 	//monthly.bap          Array(FixedString(1))           borrower assistant plan: F(forebearance), R(repayment), T(trial), O(Other), N(none), 7,9(NA) missing=X
-	//monthly.program      Array(FixedString(1))           fannie program: Y (home ready) N (no program), missing=X
+	//monthly.program      Array(FixedString(1))           fannie special eligibility program: F,H,R,O,7,9, missing=X
 	//monthly.nonIntUpb    Array(Float32)                  interest bearing UPB, missing=-1
 	//monthly.frgvUpb      Array(Float32)                  forgiven UPB, missing=-1
 	//monthly.totPrin      Array(Float32)                  delta upb
 	//monthly.servAct      Array(FixedString(1))           servicing activity: Y, N, missing=X
+	//monthly.schedPrin    Array(Float32)                  scheduled principal: prior upb * i / ((1 + i)^(rTermLgl + 1) - 1), i = curRate / 1200, missing=-1
+	//monthly.curtail      Array(Float32)                  curtailment: prior upb - upb - schedPrin, floored at 0, missing=-1
+	//monthly.smm          Array(Float32)                  single monthly mortality: (prior upb - upb - schedPrin) / (prior upb - schedPrin), 1=paid off, 0=other zero balance, missing=-1. CPR = 1 - (1 - smm)^12
+	//monthly.hpiVal       Array(Float32)                  property value: propVal indexed from fpDt by the HPI of msa (state if msa is not in the HPI), missing=-1
+	//monthly.eLtv         Array(Float32)                  mark-to-market LTV: 100 * upb / hpiVal, missing=-1
+	//monthly.incentive    Array(Float32)                  refinance incentive: curRate - market rate at month, missing=-99
 	//monthly.matDt        Array(Date)                     loan maturity date (initial), missing=1970/1/1
 	//channel              FixedString(1)                  acquisition channel: B, R, C, missing=X
 	//seller               LowCardinality(String)          name of seller, missing=unknown
@@ -74,6 +80,7 @@ func ExampleGroupBy() {
 	//occ                  FixedString(1)                  property occupancy: P (primary), S (secondary), I (investor) missing=X
 	//state                FixedString(2)                  property state postal abbreviation, missing=XX
 	//msa                  FixedString(5)                  msa/division code, missing/not in MSA=XXXXX
+	//msaOrig              FixedString(5)                  msa as reported, before the deprecated-msa crosswalk, missing/not in MSA=XXXXX
 	//zip3                 FixedString(3)                  3-digit zip , missing=XXX
 	//mi                   Float32                         mi percentage, 0-55, missing=-1
 	//amType               FixedString(3)                  amortization type: FRM, ARM, missing=XXX
@@ -107,15 +114,46 @@ func ExampleGroupBy() {
 	//file                 LowCardinality(String)          source file
 	//vintage              LowCardinality(FixedString(6))  vintage (from fpDt)
 	//propVal              Float32                         property value at origination
+	//sato                 Float32                         spread at origination: rate - market rate at origDt, missing=-99
 	//harp                 FixedString(1)                  loan is HARP: Y, N
 	//standard             LowCardinality(FixedString(1))  standard u/w process loan: Y, N
+	//agency               LowCardinality(String)          agency: FNMA, FHLMC
 	//nsDoc                FixedString(1)                  non-standard documentation: Y, N, missing=X
 	//nsUw                 FixedString(1)                  non-standard underwriting: Y, N, missing=X
 	//gGuar                FixedString(1)                  government issued/guaranteed: Y, N, missing=X
 	//negAm                FixedString(1)                  loan can neg am: Y, N, missing=X
+	//refPool              LowCardinality(String)          CRT reference pool ID, none=not in CRT file, missing=unknown
+	//dealName             LowCardinality(String)          CRT deal name, none=not in CRT file, missing=unknown
+	//modLossCum           Float32                         CRT cumulative modification loss, missing=-1
+	//ceLossCum            Float32                         CRT cumulative credit event net gain (+)/loss (-), missing=-1
+	//dqAccrInt            Float32                         delinquent accrued interest: months from lpDt to zbDt * (last curRate - 0.35) / 1200 * zbUpb, 0=not liquidated
+	//totExp               Float32                         total expenses: fclExp + fclPExp + fclLExp + fclMExp + fclTaxes, 0=not liquidated
+	//totPro               Float32                         total proceeds: fclProNet + fclProMi + fclProMw + fclProOth, 0=not liquidated
+	//netLoss              Float32                         net loss: zbUpb + dqAccrInt + totExp - totPro, 0=not liquidated
+	//severity             Float32                         loss severity: netLoss / zbUpb, 0=not liquidated
+	//dq30Dt               Date                            first month 30+ days delinquent, missing=1970/1/1
+	//dq60Dt               Date                            first month 60+ days delinquent, missing=1970/1/1
+	//dq90Dt               Date                            first month 90+ days delinquent, missing=1970/1/1
+	//dq180Dt              Date                            first month 180+ days delinquent, missing=1970/1/1
+	//modDt                Date                            first month modified (mod=Y), missing=1970/1/1
+	//fbDt                 Date                            first month in forbearance (bap=F), missing=1970/1/1
+	//zbMonth              Date                            first month with a zero balance code, missing=1970/1/1
+	//zbCode               FixedString(2)                  zero balance code of zbMonth (see zb), 00=not zero balance
+	//dq30ToLiq            Int32                           months from dq30Dt to zbMonth for liquidated loans, missing=-1
+	//dq60ToLiq            Int32                           months from dq60Dt to zbMonth for liquidated loans, missing=-1
+	//dq90ToLiq            Int32                           months from dq90Dt to zbMonth for liquidated loans, missing=-1
+	//dq180ToLiq           Int32                           months from dq180Dt to zbMonth for liquidated loans, missing=-1
+	//modToLiq             Int32                           months from modDt to zbMonth for liquidated loans, missing=-1
+	//fbToLiq              Int32                           months from fbDt to zbMonth for liquidated loans, missing=-1
+	//curtailTotal         Float32                         total of curtail
+	//bucket               Int32                           loan bucket: 0-19
 	//harpLnId             String                          loan refinanced to this HARP loan
 	//preHarpId            String                          HARP loan refinanced from this loan
-	//qa.field             Array(LowCardinality(String))   field name
+	//payHist.phMonth      Array(Date)                     month of pay history
+	//payHist.phDq         Array(Int32)                    months delinquent from pay history, missing=-1
+	//dqHist.dhMonth       Array(Date)                     month of dq history
+	//dqHist.dhDq          Array(Int32)                    months delinquent: monthly dq back-filled from pay history before first month
+	//qa.field             Array(LowCardinality(String))   field name, ts:<check> for longitudinal checks
 	//qa.cntFail           Array(Int32)                    # of months field failed qa
 	//allFail              Array(LowCardinality(String))   fields that failed QA all months
 }
@@ -0,0 +1,163 @@
+package collapse
+
+import (
+	"fmt"
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/fannie/raw"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+// selectItems splits the SELECT list sel into its items at the top-level commas
+func selectItems(sel string) []string {
+	items := make([]string, 0)
+	depth, start := 0, 0
+	for ind, c := range sel {
+		switch c {
+		case '(', '[':
+			depth++
+		case ')', ']':
+			depth--
+		case ',':
+			if depth == 0 {
+				items = append(items, strings.TrimSpace(sel[start:ind]))
+				start = ind + 1
+			}
+		}
+	}
+	return append(items, strings.TrimSpace(sel[start:]))
+}
+
+// TestNoNestedAggregates checks that no aggregate of the r CTE is over an alias of another field of r.  ClickHouse
+// substitutes the alias, which nests the aggregates and fails the query.
+func TestNoNestedAggregates(t *testing.T) {
+	aliasRx := regexp.MustCompile(`(?s)\s+AS\s+(\w+)$`)
+	aggRx := regexp.MustCompile(`groupArray\(`)
+	tokenRx := regexp.MustCompile(`\w+`)
+
+	for _, full := range []bool{false, true} {
+		q := qry
+		monthly, static := "", ""
+		if full {
+			monthly, static = fullMonthly, fullStatic
+		}
+		q = strings.Replace(strings.Replace(q, "<fullMonthly>", monthly, 1), "<fullStatic>", static, 1)
+
+		start := strings.Index(q, "r AS (\nSELECT")
+		end := strings.Index(q[start:], "\nFROM")
+		if start < 0 || end < 0 {
+			t.Fatal("cannot find the r CTE")
+		}
+		items := selectItems(q[start+len("r AS (\nSELECT") : start+end])
+
+		aliases := make(map[string]bool)
+		for _, item := range items {
+			if m := aliasRx.FindStringSubmatch(item); m != nil {
+				aliases[m[1]] = true
+			}
+		}
+		for _, item := range items {
+			own := ""
+			if m := aliasRx.FindStringSubmatch(item); m != nil {
+				own = m[1]
+			}
+			// the argument of each aggregate runs to its closing parenthesis
+			for _, loc := range aggRx.FindAllStringIndex(item, -1) {
+				depth, end := 1, loc[1]
+				for ; end < len(item) && depth > 0; end++ {
+					switch item[end] {
+					case '(':
+						depth++
+					case ')':
+						depth--
+					}
+				}
+				for _, tok := range tokenRx.FindAllString(item[loc[1]:end-1], -1) {
+					if aliases[tok] && tok != own {
+						t.Errorf("full=%v: %s aggregates the alias %s", full, own, tok)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestGroupBy runs GroupBy on a loan that pays down, goes 60 days delinquent and prepays.  It needs the ClickHouse
+// server of ExampleGroupBy and is skipped if it can't connect.
+func TestGroupBy(t *testing.T) {
+	con, err := chutils.NewConnect("127.0.0.1", "tester", "testGoNow", clickhouse.Settings{})
+	if err != nil {
+		t.Skipf("no ClickHouse: %v", err)
+	}
+	defer func() { _ = con.Close() }()
+
+	const src, table = "default.collapseTestRaw", "default.collapseTest"
+	td := *raw.TableDef
+	td.Key, td.Engine = "lnId", chutils.MergeTree
+	if e := td.Create(con, src); e != nil {
+		t.Fatal(e)
+	}
+	defer func() {
+		_, _ = con.Exec("DROP TABLE IF EXISTS " + src)
+		_, _ = con.Exec("DROP TABLE IF EXISTS " + table)
+	}()
+	ins := fmt.Sprintf(`INSERT INTO %s (lnId, month, upb, curRate, rTermLgl, zb, mod, dq, age, matDt, fpDt, payHist) VALUES
+('100000000001', '2020-01-01', 100000, 3.6, 359, '', 'N', 0, 0, '2049-12-01', '2020-01-01', ''),
+('100000000001', '2020-02-01', 99800, 3.6, 358, '', 'N', 2, 1, '2049-12-01', '2020-01-01', ''),
+('100000000001', '2020-03-01', 0, 3.6, 357, '01', 'N', 0, 2, '2049-12-01', '2020-01-01', '%s')`,
+		src, strings.Repeat("00", 24))
+	if _, e := con.Exec(ins); e != nil {
+		t.Fatal(e)
+	}
+	if e := GroupBy(src, table, "", true, con); e != nil {
+		t.Fatalf("GroupBy: %v", e)
+	}
+
+	var schedPrin, curtail, smm []float32
+	var field []string
+	var cntFail []int32
+	var phMonth, dhMonth []time.Time
+	qry := fmt.Sprintf("SELECT monthly.schedPrin, monthly.curtail, monthly.smm, qa.field, qa.cntFail, payHist.phMonth, "+
+		"dqHist.dhMonth FROM %s", table)
+	if e := con.QueryRow(qry).Scan(&schedPrin, &curtail, &smm, &field, &cntFail, &phMonth, &dhMonth); e != nil {
+		t.Fatal(e)
+	}
+
+	i := 3.6 / 1200
+	sp := 100000 * i / (math.Pow(1+i, 359) - 1)
+	tests := []struct {
+		name string
+		got  []float32
+		want []float64
+	}{
+		{"schedPrin", schedPrin, []float64{-1, sp, 99800 * i / (math.Pow(1+i, 358) - 1)}},
+		{"curtail", curtail, []float64{-1, 200 - sp, -1}},
+		{"smm", smm, []float64{-1, (200 - sp) / (100000 - sp), 1}},
+	}
+	for _, tt := range tests {
+		if len(tt.got) != len(tt.want) {
+			t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+			continue
+		}
+		for ind, w := range tt.want {
+			if math.Abs(float64(tt.got[ind])-w) > 1e-4*math.Max(1, math.Abs(w)) {
+				t.Errorf("%s = %v, want %v", tt.name, tt.got, tt.want)
+				break
+			}
+		}
+	}
+	if len(field) != 1 || field[0] != "ts:dqJump" || cntFail[0] != 1 {
+		t.Errorf("qa = %v %v, want [ts:dqJump] [1]", field, cntFail)
+	}
+	first := time.Date(2018, 3, 31, 0, 0, 0, 0, time.UTC)
+	if len(phMonth) != 24 || !phMonth[0].Equal(first) {
+		t.Errorf("payHist.phMonth = %v, want 24 months from %v", phMonth, first)
+	}
+	if len(dhMonth) != 25 || !dhMonth[0].Equal(first) {
+		t.Errorf("dqHist.dhMonth = %v, want 25 months from %v", dhMonth, first)
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/fannie/cohort"
+	"github.com/invertedv/fannie/export"
+	"github.com/invertedv/fannie/qa"
+	"github.com/invertedv/fannie/rollrate"
+	"github.com/invertedv/fannie/sample"
+	"os"
+	"strings"
+	"time"
+)
+
+// commands are the commands run by "fannie <command> <flags>".  Without a command, fannie loads the data.
+var commands = map[string]func(args []string) error{
+	"qa-report": qaReport,
+	"cohorts":   cohorts,
+	"rollrates": rollrates,
+	"sample":    sampleCmd,
+	"export":    exportCmd,
+}
+
+// conFlags adds the ClickHouse connection flags to fs.  The returned func connects once fs is parsed.
+func conFlags(fs *flag.FlagSet) func() (*chutils.Connect, error) {
+	host := fs.String("host", "127.0.0.1", "string")
+	user := fs.String("user", "default", "string")
+	password := fs.String("password", "", "string")
+	return func() (*chutils.Connect, error) {
+		return chutils.NewConnect(*host, *user, *password, clickhouse.Settings{})
+	}
+}
+
+// qaReport renders the QA summary table as JSON and/or HTML.
+//
+//	-qaSummary QA summary table.
+//	-file source file to report on. Default: all files.
+//	-json file to write the JSON report to.
+//	-html file to write the HTML report to.
+func qaReport(args []string) error {
+	fs := flag.NewFlagSet("qa-report", flag.ExitOnError)
+	connect := conFlags(fs)
+	table := fs.String("qaSummary", "", "string")
+	sourceFile := fs.String("file", "", "string")
+	jsonFile := fs.String("json", "", "string")
+	htmlFile := fs.String("html", "", "string")
+	if e := fs.Parse(args); e != nil {
+		return e
+	}
+	if *table == "" || (*jsonFile == "" && *htmlFile == "") {
+		return fmt.Errorf("%s", "qa-report requires -qaSummary and one of -json, -html")
+	}
+
+	con, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = con.Close() }()
+
+	sums, err := qa.Read(*table, *sourceFile, con)
+	if err != nil {
+		return err
+	}
+	if *jsonFile != "" {
+		if e := writeFile(*jsonFile, func(f *os.File) error { return qa.WriteJSON(f, sums) }); e != nil {
+			return e
+		}
+	}
+	if *htmlFile != "" {
+		if e := writeFile(*htmlFile, func(f *os.File) error { return qa.WriteHTML(f, sums) }); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// cohorts builds the cohort performance table from the loan table (see package cohort).
+//
+//	-table loan table built by fannie.
+//	-cohortTable table to build.
+//	-by comma-separated fields to group by in addition to vintage: state, channel, purpose, standard, harp. Optional.
+//	-period age or month. Default: age.
+func cohorts(args []string) error {
+	fs := flag.NewFlagSet("cohorts", flag.ExitOnError)
+	connect := conFlags(fs)
+	table := fs.String("table", "", "string")
+	cohortTable := fs.String("cohortTable", "", "string")
+	by := fs.String("by", "", "string")
+	period := fs.String("period", "age", "string")
+	if e := fs.Parse(args); e != nil {
+		return e
+	}
+	if *table == "" || *cohortTable == "" {
+		return fmt.Errorf("%s", "cohorts requires -table and -cohortTable")
+	}
+
+	con, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = con.Close() }()
+
+	byFields := make([]string, 0)
+	if *by != "" {
+		byFields = strings.Split(*by, ",")
+	}
+	return cohort.Build(*table, *cohortTable, byFields, *period, con)
+}
+
+// rollrates builds the delinquency transition table from the loan table (see package rollrate).
+//
+//	-table loan table built by fannie.
+//	-rollTable table to build.
+//	-by comma-separated fields to segment by: vintage, state, channel, standard. Optional.
+//	-csv file to write the count and probability matrices to. Optional.
+func rollrates(args []string) error {
+	fs := flag.NewFlagSet("rollrates", flag.ExitOnError)
+	connect := conFlags(fs)
+	table := fs.String("table", "", "string")
+	rollTable := fs.String("rollTable", "", "string")
+	by := fs.String("by", "", "string")
+	csvFile := fs.String("csv", "", "string")
+	if e := fs.Parse(args); e != nil {
+		return e
+	}
+	if *table == "" || *rollTable == "" {
+		return fmt.Errorf("%s", "rollrates requires -table and -rollTable")
+	}
+
+	con, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = con.Close() }()
+
+	byFields := make([]string, 0)
+	if *by != "" {
+		byFields = strings.Split(*by, ",")
+	}
+	if e := rollrate.Build(*table, *rollTable, byFields, con); e != nil {
+		return e
+	}
+	if *csvFile != "" {
+		return writeFile(*csvFile, func(f *os.File) error { return rollrate.WriteCSV(f, *rollTable, byFields, con) })
+	}
+	return nil
+}
+
+// sampleCmd builds a stratified sample of the loan table with a train/validation/test split (see package sample).
+//
+//	-table loan table built by fannie.
+//	-sampleTable table to build.
+//	-strata comma-separated fields to stratify by. Default: vintage,state,standard.
+//	-rate fraction of each stratum to sample. Default: 0.1.
+//	-train fraction of the buckets assigned to train, rounded to a multiple of 1/(# of buckets). Default: 0.6.
+//	-validation fraction of the buckets assigned to validation, rounded likewise.  The rest is test. Default: 0.2.
+//	-seed rotation of the buckets. Default: 0.
+func sampleCmd(args []string) error {
+	fs := flag.NewFlagSet("sample", flag.ExitOnError)
+	connect := conFlags(fs)
+	table := fs.String("table", "", "string")
+	sampleTable := fs.String("sampleTable", "", "string")
+	strata := fs.String("strata", strings.Join(sample.DefaultStrata, ","), "string")
+	rate := fs.Float64("rate", 0.1, "float64")
+	train := fs.Float64("train", 0.6, "float64")
+	validation := fs.Float64("validation", 0.2, "float64")
+	seed := fs.Uint64("seed", 0, "uint64")
+	if e := fs.Parse(args); e != nil {
+		return e
+	}
+	if *table == "" || *sampleTable == "" {
+		return fmt.Errorf("%s", "sample requires -table and -sampleTable")
+	}
+
+	con, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = con.Close() }()
+
+	smp := &sample.Sample{Strata: make([]string, 0), Rate: *rate, Train: *train, Validation: *validation, Seed: *seed}
+	if *strata != "" {
+		smp.Strata = strings.Split(*strata, ",")
+	}
+	return sample.Build(*table, *sampleTable, smp, con)
+}
+
+// exportCmd writes the loan table to files (see package export).
+//
+//	-table loan table built by fannie.
+//	-format file format: parquet, csv, tsv. Default: parquet.
+//	-dir directory to write the Parquet files to.
+//	-file file to write the CSV/TSV to.
+//	-where condition selecting the loans to write, e.g. "state = 'TX'". Default: all loans.
+//	-fields comma-separated fields to write to the CSV/TSV. Monthly fields have no "monthly." prefix. Default: all.
+//	-fromMonth first month to write to the CSV/TSV, YYYYMM. Optional.
+//	-toMonth last month to write to the CSV/TSV, YYYYMM. Optional.
+func exportCmd(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	connect := conFlags(fs)
+	table := fs.String("table", "", "string")
+	format := fs.String("format", "parquet", "string")
+	dir := fs.String("dir", "", "string")
+	fileName := fs.String("file", "", "string")
+	where := fs.String("where", "", "string")
+	fields := fs.String("fields", "", "string")
+	fromMonth := fs.String("fromMonth", "", "string")
+	toMonth := fs.String("toMonth", "", "string")
+	if e := fs.Parse(args); e != nil {
+		return e
+	}
+	if *table == "" {
+		return fmt.Errorf("%s", "export requires -table")
+	}
+	lng := &export.Long{Fields: make([]string, 0), Where: *where}
+	switch *format {
+	case "parquet":
+		if *dir == "" {
+			return fmt.Errorf("%s", "export -format parquet requires -dir")
+		}
+	case "csv", "tsv":
+		if *fileName == "" {
+			return fmt.Errorf("export -format %s requires -file", *format)
+		}
+		if *fields != "" {
+			lng.Fields = strings.Split(*fields, ",")
+		}
+		var e error
+		if lng.FromMonth, e = parseMonth(*fromMonth); e != nil {
+			return e
+		}
+		if lng.ToMonth, e = parseMonth(*toMonth); e != nil {
+			return e
+		}
+	default:
+		return fmt.Errorf("unknown export format %s, formats are: parquet, csv, tsv", *format)
+	}
+
+	con, err := connect()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = con.Close() }()
+
+	if *format == "parquet" {
+		return export.Parquet(*table, *where, *dir, con)
+	}
+	sep := ','
+	if *format == "tsv" {
+		sep = '\t'
+	}
+	return writeFile(*fileName, func(f *os.File) error { return export.Delimited(f, *table, lng, sep, con) })
+}
+
+// parseMonth parses a YYYYMM month.  The empty string is the zero time.
+func parseMonth(month string) (time.Time, error) {
+	if month == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse("200601", month)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("month %s is not YYYYMM", month)
+	}
+	return t, nil
+}
+
+// writeFile creates fileName and writes to it with write.
+func writeFile(fileName string, write func(f *os.File) error) (err error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	return write(f)
+}
@@ -0,0 +1,170 @@
+// Package export writes the table built by package collapse to files, for users who cannot reach ClickHouse.
+//
+// The formats are:
+//   - Parquet.  Files partitioned by vintage.  The nested tables are list-of-struct columns and the column
+//     descriptions are in the key-value metadata.
+//   - CSV/TSV.  Long format: a row per loan-month with the static fields repeated.  The header is the field names
+//     followed by a line of field descriptions.
+//
+// The long format is also available in ClickHouse as a view (see View).
+package export
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// column is a column of the table as reported by DESCRIBE
+type column struct {
+	name    string // name is the column name. Columns of nested tables have the form <nest>.<field>
+	chType  string // chType is the ClickHouse type
+	comment string // comment is the column description
+}
+
+// nest returns the nested table and field of c.  nest is empty if c is not in a nested table.
+func (c *column) nest() (nest string, field string) {
+	if ind := strings.Index(c.name, "."); ind > 0 {
+		return c.name[:ind], c.name[ind+1:]
+	}
+	return "", c.name
+}
+
+// base returns the type of c without Array and LowCardinality and whether c is an array
+func (c *column) base() (base string, isArray bool) {
+	base = c.chType
+	if strings.HasPrefix(base, "Array(") {
+		base, isArray = base[6:len(base)-1], true
+	}
+	if strings.HasPrefix(base, "LowCardinality(") {
+		base = base[15 : len(base)-1]
+	}
+	return base, isArray
+}
+
+// isDate returns true if the base type of c is Date
+func (c *column) isDate() bool {
+	base, _ := c.base()
+	return base == "Date"
+}
+
+// missingRx finds a numeric missing value in a description
+var missingRx = regexp.MustCompile(`missing=(-?[0-9]+(\.[0-9]+)?)(,|\s|$)`)
+
+// missing returns the numeric missing value in the description of c.  ok is false if there is none.
+func (c *column) missing() (miss float64, ok bool) {
+	m := missingRx.FindStringSubmatch(c.comment)
+	if m == nil {
+		return 0, false
+	}
+	miss, err := strconv.ParseFloat(m[1], 64)
+	return miss, err == nil
+}
+
+// isFloat returns true if the base type of c is Float32 or Float64
+func (c *column) isFloat() bool {
+	base, _ := c.base()
+	return base == "Float32" || base == "Float64"
+}
+
+// describe returns the columns of table
+func describe(table string, con *chutils.Connect) ([]*column, error) {
+	rows, err := con.Query(fmt.Sprintf("DESCRIBE %s", table))
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	cols := make([]*column, 0)
+	for rows.Next() {
+		var name, chType, defaultType, defaultExpression, comment, codec, ttl string
+		if e := rows.Scan(&name, &chType, &defaultType, &defaultExpression, &comment, &codec, &ttl); e != nil {
+			return nil, e
+		}
+		cols = append(cols, &column{name: name, chType: chType, comment: comment})
+	}
+	return cols, rows.Err()
+}
+
+// distinct returns the distinct values of field in the rows of table that satisfy where
+func distinct(table string, field string, where string, con *chutils.Connect) ([]string, error) {
+	qry := fmt.Sprintf("SELECT DISTINCT toString(%s) AS x FROM %s", field, table)
+	if where != "" {
+		qry += " WHERE " + where
+	}
+	rows, err := con.Query(qry + " ORDER BY x")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	vals := make([]string, 0)
+	for rows.Next() {
+		var val string
+		if e := rows.Scan(&val); e != nil {
+			return nil, e
+		}
+		vals = append(vals, val)
+	}
+	return vals, rows.Err()
+}
+
+// and returns the conjunction of the conditions where1, where2, either of which may be empty
+func and(where1 string, where2 string) string {
+	switch {
+	case where1 == "":
+		return where2
+	case where2 == "":
+		return where1
+	}
+	return fmt.Sprintf("(%s) AND (%s)", where1, where2)
+}
+
+// scan reads cols of the rows of table that satisfy where and calls do with the values of each row.
+// Arrays are returned as []interface{}.
+func scan(table string, cols []*column, where string, con *chutils.Connect, do func(vals []interface{}) error) error {
+	names := make([]string, 0, len(cols))
+	for _, c := range cols {
+		names = append(names, "`"+c.name+"`")
+	}
+	qry := fmt.Sprintf("SELECT %s FROM %s", strings.Join(names, ", "), table)
+	if where != "" {
+		qry += " WHERE " + where
+	}
+	rows, err := con.Query(qry)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	cts, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		dest := make([]interface{}, len(cts))
+		for ind, ct := range cts {
+			dest[ind] = reflect.New(ct.ScanType()).Interface()
+		}
+		if e := rows.Scan(dest...); e != nil {
+			return e
+		}
+		vals := make([]interface{}, len(dest))
+		for ind, d := range dest {
+			v := reflect.ValueOf(d).Elem()
+			if v.Kind() != reflect.Slice {
+				vals[ind] = v.Interface()
+				continue
+			}
+			arr := make([]interface{}, v.Len())
+			for j := 0; j < v.Len(); j++ {
+				arr[j] = v.Index(j).Interface()
+			}
+			vals[ind] = arr
+		}
+		if e := do(vals); e != nil {
+			return e
+		}
+	}
+	return rows.Err()
+}
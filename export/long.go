@@ -0,0 +1,161 @@
+package export
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"io"
+	"strings"
+	"time"
+)
+
+// Long specifies a long-format export, which has a row per loan-month with the static fields repeated
+type Long struct {
+	Fields    []string  // Fields are the fields to export.  Monthly fields don't have the "monthly." prefix. If empty, all.
+	FromMonth time.Time // FromMonth is the first month to export. If zero, there is no first month.
+	ToMonth   time.Time // ToMonth is the last month to export. If zero, there is no last month.
+	Where     string    // Where selects the loans to export. If empty, all loans.
+}
+
+// View creates (or replaces) the view view of table in long format.
+func View(table string, view string, con *chutils.Connect) error {
+	cols, err := describe(table, con)
+	if err != nil {
+		return err
+	}
+	exprs, outCols, err := longSelect(cols, nil)
+	if err != nil {
+		return err
+	}
+	sel := make([]string, len(exprs))
+	for ind, c := range outCols {
+		sel[ind] = fmt.Sprintf("%s AS `%s`", exprs[ind], c.name)
+	}
+	_, err = con.Exec(fmt.Sprintf("CREATE OR REPLACE VIEW %s AS SELECT %s FROM %s ARRAY JOIN monthly", view,
+		strings.Join(sel, ", "), table))
+	return err
+}
+
+// Delimited writes table in long format to w with the delimiter sep.  The first line has the field names and the
+// second line has the field descriptions.
+func Delimited(w io.Writer, table string, lng *Long, sep rune, con *chutils.Connect) error {
+	cols, err := describe(table, con)
+	if err != nil {
+		return err
+	}
+	exprs, outCols, err := longSelect(cols, lng.Fields)
+	if err != nil {
+		return err
+	}
+	sel := make([]string, len(exprs))
+	for ind, expr := range exprs {
+		sel[ind] = fmt.Sprintf("toString(%s)", expr)
+	}
+	where := lng.Where
+	if !lng.FromMonth.IsZero() {
+		where = and(where, fmt.Sprintf("monthly.month >= toDate('%s')", lng.FromMonth.Format("2006-01-02")))
+	}
+	if !lng.ToMonth.IsZero() {
+		where = and(where, fmt.Sprintf("monthly.month <= toDate('%s')", lng.ToMonth.Format("2006-01-02")))
+	}
+	qry := fmt.Sprintf("SELECT %s FROM %s ARRAY JOIN monthly", strings.Join(sel, ", "), table)
+	if where != "" {
+		qry += " WHERE " + where
+	}
+	rows, err := con.Query(qry)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	cw := csv.NewWriter(w)
+	cw.Comma = sep
+	names, descs := make([]string, len(outCols)), make([]string, len(outCols))
+	for ind, c := range outCols {
+		names[ind], descs[ind] = c.name, c.comment
+	}
+	if e := cw.Write(names); e != nil {
+		return e
+	}
+	if e := cw.Write(descs); e != nil {
+		return e
+	}
+
+	vals := make([]string, len(outCols))
+	dest := make([]interface{}, len(outCols))
+	for ind := range vals {
+		dest[ind] = &vals[ind]
+	}
+	for rows.Next() {
+		if e := rows.Scan(dest...); e != nil {
+			return e
+		}
+		for ind := range vals {
+			vals[ind] = strings.TrimRight(vals[ind], "\x00")
+		}
+		if e := cw.Write(vals); e != nil {
+			return e
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// longSelect returns the source expressions and columns of the long format of cols restricted to fields (all if
+// fields is empty).  The static fields are repeated and the monthly fields lose the "monthly." prefix.  The other
+// nested tables (qa, payHist, dqHist) aren't monthly, so are not included.
+func longSelect(cols []*column, fields []string) (exprs []string, outCols []*column, err error) {
+	static, monthly := make(map[string]bool), false
+	for _, c := range cols {
+		nest, _ := c.nest()
+		if nest == "" {
+			static[c.name] = true
+		}
+		monthly = monthly || nest == "monthly"
+	}
+	if !monthly {
+		return nil, nil, fmt.Errorf("%s", "table has no monthly nest")
+	}
+	all := make([]*column, 0)
+	src := make(map[string]string) // src maps the long name to the source column
+	for _, c := range cols {
+		nest, field := c.nest()
+		switch {
+		case nest == "":
+			all = append(all, c)
+			src[c.name] = "`" + c.name + "`"
+		case nest == "monthly":
+			// keep the prefix if the name is taken by a static field
+			name := field
+			if static[field] {
+				name = c.name
+			}
+			base, _ := c.base()
+			all = append(all, &column{name: name, chType: base, comment: c.comment})
+			src[name] = "`" + c.name + "`"
+		}
+	}
+	outCols = all
+	if len(fields) > 0 {
+		outCols = make([]*column, 0, len(fields))
+		for _, f := range fields {
+			var col *column
+			for _, c := range all {
+				if c.name == f {
+					col = c
+				}
+			}
+			if col == nil {
+				return nil, nil, fmt.Errorf("field %s is not in the long format", f)
+			}
+			outCols = append(outCols, col)
+		}
+	}
+	for _, c := range outCols {
+		exprs = append(exprs, src[c.name])
+	}
+	return exprs, outCols, nil
+}
@@ -0,0 +1,57 @@
+package export
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLongSelect(t *testing.T) {
+	cols := []*column{
+		{name: "lnId", chType: "String", comment: "loan id"},
+		{name: "rate", chType: "Float32", comment: "note rate"},
+		{name: "monthly.month", chType: "Array(Date)", comment: "month"},
+		{name: "monthly.rate", chType: "Array(Float32)", comment: "current rate"},
+		{name: "monthly.servicer", chType: "Array(LowCardinality(String))", comment: "servicer"},
+		{name: "qa.field", chType: "Array(String)", comment: "qa field"},
+	}
+	tests := []struct {
+		name    string
+		cols    []*column
+		fields  []string
+		exprs   []string
+		out     []string
+		wantErr bool
+	}{
+		{"all", cols, nil,
+			[]string{"`lnId`", "`rate`", "`monthly.month`", "`monthly.rate`", "`monthly.servicer`"},
+			[]string{"lnId", "rate", "month", "monthly.rate", "servicer"}, false},
+		{"fields", cols, []string{"servicer", "lnId", "monthly.rate"},
+			[]string{"`monthly.servicer`", "`lnId`", "`monthly.rate`"},
+			[]string{"servicer", "lnId", "monthly.rate"}, false},
+		{"unknown field", cols, []string{"lnId", "field"}, nil, nil, true},
+		{"no monthly", cols[:2], nil, nil, nil, true},
+	}
+	for _, tt := range tests {
+		exprs, outCols, err := longSelect(tt.cols, tt.fields)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		out := make([]string, 0, len(outCols))
+		for _, c := range outCols {
+			out = append(out, c.name)
+		}
+		if !reflect.DeepEqual(exprs, tt.exprs) || !reflect.DeepEqual(out, tt.out) {
+			t.Errorf("%s: got %v %v, want %v %v", tt.name, exprs, out, tt.exprs, tt.out)
+		}
+	}
+
+	// the monthly fields are not arrays in the long format
+	_, outCols, _ := longSelect(cols, []string{"servicer"})
+	if outCols[0].chType != "String" {
+		t.Errorf("servicer has type %s, want String", outCols[0].chType)
+	}
+}
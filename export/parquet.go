@@ -0,0 +1,201 @@
+package export
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/xitongsys/parquet-go/parquet"
+	"github.com/xitongsys/parquet-go/writer"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Parquet writes the rows of table that satisfy where (all rows if where is empty) to Parquet files partitioned by
+// vintage.  The file of each vintage is dir/vintage=<vintage>/part-0.parquet.  The nested tables become
+// list-of-struct columns and the column descriptions are written to the key-value metadata, keyed by column name.
+func Parquet(table string, where string, dir string, con *chutils.Connect) error {
+	cols, err := describe(table, con)
+	if err != nil {
+		return err
+	}
+	schema, err := parquetSchema(cols)
+	if err != nil {
+		return err
+	}
+	vintages, err := distinct(table, "vintage", where, con)
+	if err != nil {
+		return err
+	}
+	for _, vintage := range vintages {
+		part := filepath.Join(dir, "vintage="+vintage)
+		if e := os.MkdirAll(part, 0755); e != nil {
+			return e
+		}
+		if e := writeParquet(filepath.Join(part, "part-0.parquet"), schema, table, cols,
+			and(where, fmt.Sprintf("vintage = '%s'", vintage)), con); e != nil {
+			return e
+		}
+	}
+	return nil
+}
+
+// writeParquet writes the rows of table that satisfy where to fileName
+func writeParquet(fileName string, schema string, table string, cols []*column, where string,
+	con *chutils.Connect) (err error) {
+	f, err := os.Create(fileName)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	pw, err := writer.NewJSONWriterFromWriter(schema, f, 1)
+	if err != nil {
+		return err
+	}
+	pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, keyValue("source", table))
+	// fills are the values of NaN and Inf: the missing value of the column, or null if it has none
+	fills := make([]interface{}, len(cols))
+	for ind, c := range cols {
+		pw.Footer.KeyValueMetadata = append(pw.Footer.KeyValueMetadata, keyValue(c.name, c.comment))
+		if miss, ok := c.missing(); ok {
+			fills[ind] = miss
+		}
+	}
+
+	err = scan(table, cols, where, con, func(vals []interface{}) error {
+		rec := make(map[string]interface{})
+		for ind, c := range cols {
+			nest, field := c.nest()
+			if nest == "" {
+				rec[c.name] = parquetValue(vals[ind], c.isDate(), fills[ind])
+				continue
+			}
+			// nested fields are arrays of the same length -- these become a list of structs
+			arr := vals[ind].([]interface{})
+			if _, ok := rec[nest]; !ok {
+				elems := make([]map[string]interface{}, len(arr))
+				for j := range elems {
+					elems[j] = make(map[string]interface{})
+				}
+				rec[nest] = elems
+			}
+			elems := rec[nest].([]map[string]interface{})
+			for j := 0; j < len(arr) && j < len(elems); j++ {
+				elems[j][field] = parquetValue(arr[j], c.isDate(), fills[ind])
+			}
+		}
+		js, e := json.Marshal(rec)
+		if e != nil {
+			return e
+		}
+		return pw.Write(string(js))
+	})
+	if err != nil {
+		return err
+	}
+	return pw.WriteStop()
+}
+
+// parquetValue converts v to the value the Parquet JSON writer expects.  date is true if v is a Date rather than
+// a DateTime.  fill replaces NaN and Inf, which JSON can't represent.
+func parquetValue(v interface{}, date bool, fill interface{}) interface{} {
+	switch x := v.(type) {
+	case float32:
+		if math.IsNaN(float64(x)) || math.IsInf(float64(x), 0) {
+			return fill
+		}
+	case float64:
+		if math.IsNaN(x) || math.IsInf(x, 0) {
+			return fill
+		}
+	case time.Time:
+		// DATE is days since the epoch
+		if date {
+			return int32(x.Unix() / 86400)
+		}
+		return x.UnixMilli()
+	case []interface{}:
+		arr := make([]interface{}, len(x))
+		for ind, xv := range x {
+			arr[ind] = parquetValue(xv, date, fill)
+		}
+		return arr
+	case string:
+		return strings.TrimRight(x, "\x00")
+	}
+	return v
+}
+
+// keyValue returns a Parquet key-value metadata entry
+func keyValue(key string, value string) *parquet.KeyValue {
+	return &parquet.KeyValue{Key: key, Value: &value}
+}
+
+// parquetSchema returns the JSON schema of the Parquet file for cols
+func parquetSchema(cols []*column) (string, error) {
+	type node struct {
+		Tag    string  `json:"Tag"`
+		Fields []*node `json:"Fields,omitempty"`
+	}
+	root := &node{Tag: "name=parquet_go_root, repetitiontype=REQUIRED"}
+	nests := make(map[string]*node)
+	for _, c := range cols {
+		base, isArray := c.base()
+		pType, err := parquetType(base)
+		if err != nil {
+			return "", fmt.Errorf("column %s: %v", c.name, err)
+		}
+		// floats without a missing value are null if NaN or Inf
+		rep := "REQUIRED"
+		if _, ok := c.missing(); c.isFloat() && !ok {
+			rep = "OPTIONAL"
+		}
+		nest, field := c.nest()
+		switch {
+		case nest != "":
+			if _, ok := nests[nest]; !ok {
+				nests[nest] = &node{Tag: "name=element, repetitiontype=REQUIRED"}
+				root.Fields = append(root.Fields, &node{Tag: fmt.Sprintf("name=%s, type=LIST, repetitiontype=REQUIRED", nest),
+					Fields: []*node{nests[nest]}})
+			}
+			nests[nest].Fields = append(nests[nest].Fields,
+				&node{Tag: fmt.Sprintf("name=%s, %s, repetitiontype=%s", field, pType, rep)})
+		case isArray:
+			root.Fields = append(root.Fields, &node{Tag: fmt.Sprintf("name=%s, type=LIST, repetitiontype=REQUIRED", c.name),
+				Fields: []*node{{Tag: fmt.Sprintf("name=element, %s, repetitiontype=%s", pType, rep)}}})
+		default:
+			root.Fields = append(root.Fields, &node{Tag: fmt.Sprintf("name=%s, %s, repetitiontype=%s", c.name, pType, rep)})
+		}
+	}
+	js, err := json.Marshal(root)
+	return string(js), err
+}
+
+// parquetType returns the Parquet type of the ClickHouse type chType
+func parquetType(chType string) (string, error) {
+	switch {
+	case chType == "String" || strings.HasPrefix(chType, "FixedString"):
+		return "type=BYTE_ARRAY, convertedtype=UTF8", nil
+	case chType == "Int8" || chType == "Int16" || chType == "Int32" || chType == "UInt8" || chType == "UInt16":
+		return "type=INT32", nil
+	case chType == "Int64" || chType == "UInt32":
+		return "type=INT64", nil
+	case chType == "UInt64":
+		return "type=INT64, convertedtype=UINT_64", nil
+	case chType == "Float32":
+		return "type=FLOAT", nil
+	case chType == "Float64":
+		return "type=DOUBLE", nil
+	case chType == "Date":
+		return "type=INT32, convertedtype=DATE", nil
+	case strings.HasPrefix(chType, "DateTime"):
+		return "type=INT64, convertedtype=TIMESTAMP_MILLIS", nil
+	}
+	return "", fmt.Errorf("unsupported type %s", chType)
+}
@@ -11,9 +11,22 @@
 //   - property value at origination
 //   - harp - Y/N flag, Y=HARP loan.
 //   - file name from which the loan was loaded
+//   - agency - FNMA or FHLMC
+//   - msaOrig - the msa as reported.  The msa field has deprecated codes replaced by their current codes.
+//   - payHist - the 24-month pay history decoded to months delinquent by calendar month (nested).
+//   - dqHist - months delinquent back-filled from payHist for the months before the loan first appears (nested).
+//   - monthly prepayment measures (nested) - schedPrin, curtail, smm - and the loan-level curtailTotal.
+//   - mark-to-market LTV (nested) - hpiVal and eLtv from the -hpi house price index.
+//   - rate spreads - sato at origination and monthly.incentive (nested) from the -rates market rate series.
+//   - loss fields for liquidated loans following Fannie's formulas - dqAccrInt, totExp, totPro, netLoss, severity.
+//   - milestone dates - first month 30/60/90/180+ days delinquent, modified, in forbearance and zero balance (with
+//     the code), and the months from each milestone to liquidation.
 //   - QA results. There are three sets of fields:
 //   - The nested table qa that has two arrays:
 //   - field.  The name of a field that has validation issues.
+//     Cross-field consistency rules (e.g. cltv < ltv) that fail are named xf:<rule>, e.g. xf:cltvLtLtv.
+//     Longitudinal checks of the monthly history (e.g. upb increasing without a modification) are named
+//     ts:<check>, e.g. ts:upbUp. For these, cntFail is the number of months failing the check.
 //   - cntFail. The number of months for which this field failed qa.  For static fields, this value will
 //     be 1.
 //   - allFail.  An array of field names which failed for qa.  For monthly fields, this means the field failed for all months.
@@ -26,23 +39,98 @@
 //	-password ClickHouse password for user. Default: <empty>.
 //	-table ClickHouse table in which to insert the data.
 //	-maptable.  Clickhouse table that maps pre-HARP loan ids to HARP ids.  This table is both created and used by the package.
+//	        Optional: without it, harpLnId and preHarpId are empty.  Not used by -dataset multifamily.
 //	-create if Y, then the table is created/reset. Default: Y.
-//	-dir directory with Fannie Mae text files.
+//	-dir directory with Fannie Mae text files.  The files may be compressed (.gz, .zst) or in .zip archives.
 //	-tmp ClickHouse database to use for temporary tables.
 //	-concur # of concurrent processes to use in loading monthly files. Default: 1.
 //	-memory max memory usage by ClickHouse.  Default: 40000000000.
 //	-groupby max_bytes_before_external_groupby ClickHouse paramter. Default: 20000000000.
+//	-manifest ClickHouse table that records the files loaded into -table. Created if it does not exist. Optional.
+//	-release tag for the data release recorded in the manifest. Optional.
+//	-dataset fannie, freddie or multifamily. Default: fannie.
+//	-layout record layout of the files. If omitted, the layout is detected from the number of columns.
+//	        Layouts: sf2020 (standard), sf2020excl (non-standard), crt (CAS/CIRT).
+//	-msaMap file with the crosswalk of deprecated MSA codes to current codes.  Default: the crosswalk embedded in
+//	        package raw.  The original code is kept in the msaOrig field.
+//	-hpi FHFA-style house price index file (CBSA and state level, monthly or quarterly).  If given, monthly.hpiVal
+//	        and monthly.eLtv index propVal by msa (state if msa is not in the file) from fpDt.  Optional.
+//	-rates PMMS-style file of weekly or monthly market mortgage rates, with the date in the first column and the
+//	        rate in the pmms30 column.  If given, sato (rate less the market rate at origDt) and monthly.incentive
+//	        (curRate less the market rate) are populated.  Optional.
+//	-qaSummary ClickHouse table with a per-file, per-field summary of the validation.  Created if it does not
+//	        exist. Optional.
+//	-fatal comma-separated fields whose failure sends the row to quarantine rather than -table. Default: lnId,month.
+//	-quarantine ClickHouse table for rows that fail a -fatal field.  Created if it does not exist. Optional.  If
+//	        omitted, these rows are dropped.
+//	-full if Y, the columns of the Fannie layout that the lean schema drops (e.g. list prices, current fico,
+//	        ARM terms) are loaded.  Static fields go in the table, monthly fields in monthly.  Default: N.
+//	-buckets # of values of the bucket field, which is calculated from lnId.  Default: 20.
+//	-longView ClickHouse view of -table in long format (a row per loan-month) to create.  Optional.
+//	-resume if Y, files already loaded into -table are skipped and partially loaded files are backed out and
+//	        reloaded.  Requires -manifest and -create N. Default: N.
 //
-// The non-standard loans have four additional fields.  This package recognizes whether the file is standard or not.
+// The non-standard loans have four additional fields.  This package recognizes whether the file is standard or not
+// from the number of columns.  Each supported record layout is registered in package raw.
+// The CAS/CIRT credit risk transfer files have the standard layout with the reference pool ID populated.  These
+// add the refPool, dealName, modLossCum and ceLossCum fields to the table.
 // A combined table can be built by running the app twice pointing to the same -table.
 // On the first run, set -create Y and set -create N for the second run.
 //
+// If a run fails part way through, it can be restarted with -resume Y (and the same -manifest).  Files that
+// finished are skipped, and the loans of the file that was in progress are deleted from -table and reloaded.
+//
 // Note: for this package to run correctly, the standard loans should be loaded first, so that the table that
 // maps HARP loans to their corresponding pre-HARP loan is loaded and available.  This table isn't needed after all the
 // files are loaded.
 //
 // See the example under package collapse for the structure of the table.
 //
+// The QA summary table is rendered as JSON and/or HTML by the qa-report command:
+//
+//	fannie qa-report -qaSummary <table> [-file <source file>] [-json <file>] [-html <file>] [-host, -user, -password]
+//
+// Vintage performance by loan age or calendar month (loans, upb, dq buckets, CPR/CDR, cumulative loss) is built
+// into a table by the cohorts command.  The cohorts may also be grouped by state, channel, purpose, standard and harp:
+//
+//	fannie cohorts -table <table> -cohortTable <table> [-by <fields>] [-period age|month] [-host, -user, -password]
+//
+// Month-over-month transition matrices between current, 30, 60, 90, 120+, FCL, REO, prepaid and other are built by
+// the rollrates command, optionally segmented by vintage, state, channel and standard:
+//
+//	fannie rollrates -table <table> -rollTable <table> [-by <fields>] [-csv <file>] [-host, -user, -password]
+//
+// A reproducible stratified sample, with each loan assigned to train, validation or test in the split field, is
+// built from the bucket field by the sample command.  The split fractions are multiples of 1/(# of buckets):
+//
+//	fannie sample -table <table> -sampleTable <table> [-strata <fields>] [-rate <rate>] [-train <frac>]
+//	    [-validation <frac>] [-seed <seed>] [-host, -user, -password]
+//
+// The table, or the rows satisfying -where, is written to Parquet files partitioned by vintage by the export command.
+// The monthly and qa nested tables become list-of-struct columns and the field descriptions go into the metadata:
+//
+//	fannie export -table <table> -dir <directory> [-format parquet] [-where <condition>] [-host, -user, -password]
+//
+// With -format csv or tsv, the export is in long format: a row per loan-month with the static fields repeated.  The
+// first line is the field names and the second the field descriptions.  -fields selects the fields and -fromMonth,
+// -toMonth (YYYYMM) the months:
+//
+//	fannie export -table <table> -format csv -file <file> [-fields <fields>] [-fromMonth <YYYYMM>]
+//	    [-toMonth <YYYYMM>] [-where <condition>] [-host, -user, -password]
+//
+// Go code can read the table with package loans, which streams each loan as a Loan struct with the monthly, qa,
+// payHist and dqHist nests as slices of structs.  Loans may be filtered by vintage, state, lnId and bucket.
+//
+// Legacy (pre-2020) releases, which have an Acquisition and a Performance file for each quarter, are also supported.
+// The two files are joined as they are read to produce the same table.
+//
+// The Freddie Mac Single-Family Loan-Level dataset is loaded with -dataset freddie (see package freddie).  The Freddie
+// data is mapped onto the Fannie fields, so both can be loaded into the same table. The agency field identifies the
+// source.
+//
+// The Fannie Mae Multifamily Loan Performance data is loaded with -dataset multifamily (see package multifamily).
+// The multifamily fields are unrelated to the single-family fields, so these go into their own -table.
+//
 // The data is available at https://datadynamics.fanniemae.com/data-dynamics/#/reportMenu;category=HP.
 package main
 
@@ -52,6 +140,11 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/invertedv/chutils"
 	"github.com/invertedv/fannie/collapse"
+	"github.com/invertedv/fannie/export"
+	"github.com/invertedv/fannie/freddie"
+	"github.com/invertedv/fannie/manifest"
+	"github.com/invertedv/fannie/multifamily"
+	"github.com/invertedv/fannie/qa"
 	"github.com/invertedv/fannie/raw"
 	"log"
 	"os"
@@ -62,6 +155,14 @@ import (
 
 func main() {
 	var err error
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			if e := cmd(os.Args[2:]); e != nil {
+				log.Fatalln(e)
+			}
+			return
+		}
+	}
 	host := flag.String("host", "127.0.0.1", "string")
 	user := flag.String("user", "default", "string")
 	password := flag.String("password", "", "string")
@@ -73,9 +174,36 @@ func main() {
 	nConcur := flag.Int("concur", 1, "int")
 	maxMemory := flag.Int64("memory", 40000000000, "int64")
 	maxGroupby := flag.Int64("groupby", 20000000000, "int64")
+	manifestTable := flag.String("manifest", "", "string")
+	release := flag.String("release", "", "string")
+	resume := flag.String("resume", "N", "string")
+	layout := flag.String("layout", "", "string")
+	dataset := flag.String("dataset", "fannie", "string")
+	msaMap := flag.String("msaMap", "", "string")
+	hpi := flag.String("hpi", "", "string")
+	rates := flag.String("rates", "", "string")
+	buckets := flag.Int("buckets", collapse.Buckets, "int")
+	qaSummary := flag.String("qaSummary", "", "string")
+	quarantineTable := flag.String("quarantine", "", "string")
+	fatal := flag.String("fatal", strings.Join(raw.Fatal, ","), "string")
+	full := flag.String("full", "N", "string")
+	longView := flag.String("longView", "", "string")
 
 	flag.Parse()
 	createTable := *create == "Y" || *create == "y"
+	resumeLoad := *resume == "Y" || *resume == "y"
+	raw.SetFull(*full == "Y" || *full == "y")
+	// after SetFull, since -full adds fields
+	if e := raw.SetFatal(strings.Split(*fatal, ",")); e != nil {
+		log.Fatalln(e)
+	}
+	if *buckets < 1 {
+		log.Fatalln(fmt.Errorf("%s", "-buckets must be at least 1"))
+	}
+	collapse.Buckets = *buckets
+	if resumeLoad && (createTable || *manifestTable == "") {
+		log.Fatalln(fmt.Errorf("%s", "-resume Y requires -create N and a -manifest table"))
+	}
 
 	if (*srcDir)[len(*srcDir)-1] != '/' {
 		*srcDir += "/"
@@ -93,6 +221,50 @@ func main() {
 			log.Fatalln(e)
 		}
 	}()
+	if *msaMap != "" {
+		if e := raw.LoadCrosswalk(*msaMap); e != nil {
+			log.Fatalln(e)
+		}
+	}
+	fmt.Printf("MSA crosswalk version %s\n", raw.MsaCrosswalk.Version)
+	if *hpi != "" {
+		if e := raw.LoadHPI(*hpi); e != nil {
+			log.Fatalln(e)
+		}
+	}
+	if *rates != "" {
+		if e := raw.LoadRates(*rates); e != nil {
+			log.Fatalln(e)
+		}
+	}
+
+	// load loads a file into the tmp table, group collapses the tmp table into the output table
+	var (
+		load  func(sourceFile string, table string) error
+		group = func(sourceTable string, table string, create bool) error {
+			return collapse.GroupBy(sourceTable, table, *mapTable, create, con)
+		}
+	)
+	switch *dataset {
+	case "fannie":
+		load = func(sourceFile string, table string) error {
+			return raw.LoadRaw(sourceFile, table, *layout, true, *nConcur, con)
+		}
+	case "freddie":
+		load = func(sourceFile string, table string) error {
+			return freddie.LoadRaw(sourceFile, table, true, *nConcur, con)
+		}
+	case "multifamily":
+		load = func(sourceFile string, table string) error {
+			return multifamily.LoadRaw(sourceFile, table, true, *nConcur, con)
+		}
+		group = func(sourceTable string, table string, create bool) error {
+			return multifamily.GroupBy(sourceTable, table, create, con)
+		}
+	default:
+		log.Fatalln(fmt.Errorf("unknown dataset: %s", *dataset))
+	}
+
 	// holds the set of files to work through
 	fileList := make([]string, 0)
 
@@ -101,24 +273,63 @@ func main() {
 		log.Fatalln(fmt.Errorf("error reading directory: %s", *srcDir))
 	}
 
-	gotMap := false // if true, this directory has the mapping of pre-HARP to HARP loans
-	// build the file list
+	mapFile := "" // if not empty, this directory has the mapping of pre-HARP to HARP loans
+	// build the file list. Files may be compressed (.gz, .zst) or in .zip archives.
 	for _, f := range dir {
-		if strings.Contains(f.Name(), ".csv") && !strings.Contains(f.Name(), "Loan") {
-			fileList = append(fileList, f.Name())
+		members, e := raw.Members(*srcDir + f.Name())
+		if e != nil {
+			log.Fatalln(e)
 		}
-		if f.Name() == "Loan_Mapping.txt" {
-			gotMap = true
+		for _, member := range members {
+			fileName := strings.TrimPrefix(member, *srcDir)
+			baseName := raw.BaseName(fileName)
+			if isSource(*dataset, baseName) {
+				fileList = append(fileList, fileName)
+			}
+			if baseName == "Loan_Mapping.txt" {
+				mapFile = fileName
+			}
 		}
 	}
 	if len(fileList) == 0 {
-		log.Fatalln(fmt.Errorf("%s", "directory has no .csv files"))
+		log.Fatalln(fmt.Errorf("directory has no %s files", *dataset))
+	}
+	// the HARP map is joined by the fannie/freddie collapse.  Without -mapTable, collapse uses an empty map.
+	if *mapTable != "" && (*dataset == "fannie" || *dataset == "freddie") {
+		if mapFile != "" {
+			if e := raw.LoadHarpMap(*srcDir+mapFile, *mapTable, con); e != nil {
+				log.Fatalln(e)
+			}
+		}
+		// collapse joins to the map table, so it must exist
+		if e := raw.CreateHarpMap(*mapTable, con); e != nil {
+			log.Fatalln(e)
+		}
+	}
+	// the QA summary and quarantine are done by package raw, which multifamily doesn't use
+	if (*qaSummary != "" || *quarantineTable != "") && *dataset == "multifamily" {
+		log.Fatalln(fmt.Errorf("%s", "-qaSummary and -quarantine are not supported for -dataset multifamily"))
+	}
+	if *qaSummary != "" {
+		if e := qa.Create(*qaSummary, con); e != nil {
+			log.Fatalln(e)
+		}
 	}
-	if gotMap {
-		if e := raw.LoadHarpMap(*srcDir+"Loan_Mapping.txt", *mapTable, con); e != nil {
+	if *quarantineTable != "" {
+		if e := raw.CreateQuarantine(*quarantineTable, con); e != nil {
 			log.Fatalln(e)
 		}
 	}
+	if *manifestTable != "" {
+		if e := manifest.Create(*manifestTable, con); e != nil {
+			log.Fatalln(e)
+		}
+		if createTable {
+			if e := manifest.Reset(*manifestTable, *table, con); e != nil {
+				log.Fatalln(e)
+			}
+		}
+	}
 
 	sort.Strings(fileList)
 	step1Time := 0.0
@@ -126,24 +337,94 @@ func main() {
 	for ind, fileName := range fileList {
 		fullFile := *srcDir + fileName
 		tmpTable := *tmp + ".source"
+		var entry *manifest.Entry
+		if *manifestTable != "" {
+			var e error
+			if entry, e = manifest.NewEntry(fullFile, *table, *release); e != nil {
+				log.Fatalln(e)
+			}
+			if resumeLoad {
+				last, e := entry.Last(*manifestTable, con)
+				if e != nil {
+					log.Fatalln(e)
+				}
+				if last != nil && last.Status == manifest.Complete {
+					fmt.Printf("Skipping %s. %d out of %d, already loaded\n", fileName, ind+1, len(fileList))
+					continue
+				}
+				// back out the loans of a partial load
+				if last != nil {
+					if e := manifest.Unload(*table, last.File, con); e != nil {
+						log.Fatalln(e)
+					}
+				}
+			}
+			if e := entry.Write(*manifestTable, con); e != nil {
+				log.Fatalln(e)
+			}
+		}
 		s := time.Now()
-		if e := raw.LoadRaw(fullFile, tmpTable, true, *nConcur, con); e != nil {
+		if e := load(fullFile, tmpTable); e != nil {
 			log.Fatalln(e)
 		}
+		if *qaSummary != "" {
+			if e := qa.Write(*qaSummary, fullFile, raw.Summaries(), con); e != nil {
+				log.Fatalln(e)
+			}
+		}
+		if *quarantineTable != "" {
+			if e := raw.WriteQuarantine(*quarantineTable, fullFile, raw.Quarantine(), con); e != nil {
+				log.Fatalln(e)
+			}
+		}
 		step1 := time.Since(s).Minutes()
 		s = time.Now()
-		if e := collapse.GroupBy("tmp.source", *table, *mapTable, createTable, con); e != nil {
+		if e := group("tmp.source", *table, createTable); e != nil {
 			log.Fatalln(e)
 		}
 		step2 := time.Since(s).Minutes()
 		createTable = false
-		fmt.Printf("Done with %s. %d out of %d ,times: %0.2f, %0.2f minutes\n", fileName, ind+1, len(fileList), step1, step2)
+		if entry != nil {
+			if e := entry.Count(tmpTable, con); e != nil {
+				log.Fatalln(e)
+			}
+			entry.Finish, entry.Status = time.Now(), manifest.Complete
+			if e := entry.Write(*manifestTable, con); e != nil {
+				log.Fatalln(e)
+			}
+		}
+		fmt.Printf("Done with %s. %d out of %d ,times: %0.2f, %0.2f minutes", fileName, ind+1, len(fileList), step1, step2)
+		// the counts are kept by package raw, which multifamily doesn't use
+		if *dataset != "multifamily" {
+			fmt.Printf(", %d MSAs remapped, %d rows quarantined, %d rows without static fields",
+				raw.Remapped(), len(raw.Quarantine()), raw.Unmatched())
+		}
+		fmt.Println()
 		step1Time += step1
 		step2Time += step2
 	}
 	step1Time /= 60.0
 	step2Time /= 60.0
 	fmt.Printf("step1 time: %0.2f step2 time: %0.2f hours, total: %0.2f\n", step1Time, step2Time, step1Time+step2Time)
+	if *longView != "" {
+		if e := export.View(*table, *longView, con); e != nil {
+			log.Fatalln(e)
+		}
+	}
 	// clean up
 	_, _ = con.Exec(fmt.Sprintf("DROP TABLE %s.source", *tmp))
 }
+
+// isSource returns true if baseName is a file of the dataset to load.
+func isSource(dataset string, baseName string) bool {
+	switch dataset {
+	case "freddie":
+		// the origination files are read along with the monthly files
+		return freddie.IsMonthly(baseName)
+	case "multifamily":
+		return multifamily.IsSource(baseName)
+	}
+	// legacy files: Performance_*.txt are loaded, the Acquisition_*.txt files are read along with them
+	legacy := strings.HasPrefix(baseName, "Performance_")
+	return (strings.Contains(baseName, ".csv") || legacy) && !strings.Contains(baseName, "Loan")
+}
@@ -0,0 +1,177 @@
+// Package freddie loads the Freddie Mac Single-Family Loan-Level dataset.
+//
+// Freddie releases two files per period:
+//   - origination (historical_data_YYYYQn.txt or sample_orig_YYYY.txt). One row per loan with the static fields.
+//   - monthly performance (historical_data_time_YYYYQn.txt or sample_svcg_YYYY.txt). One row per loan per month.
+//
+// The files are joined and mapped onto the Fannie fields, codes and missing values as they are read, so the
+// data flows through package raw and package collapse exactly as the Fannie data does.  The agency field is FHLMC.
+// Fields that Freddie does not have take on their default (or missing) values.
+//
+// The mapping of Freddie codes to Fannie codes:
+//   - dates are converted from YYYYMM to MMYYYY.
+//   - zip3 is the first three digits of the Freddie postal code.
+//   - purpose N (no cash-out refi) is U.
+//   - valMthd 1 (ACE) is W, 2 (full appraisal) is A, 3 (other appraisal) is O, 4 (ACE + PDR) is P.
+//   - program H (Home Possible) is H, F (HFA Advantage) is F, R (Refi Possible) is R, 9 is 9.  Other codes are
+//     missing.
+//   - mod and sConform are N if blank.
+//
+// Freddie's Expenses is the total of the legal, maintenance, taxes and insurance and miscellaneous expenses.  Only the
+// components are loaded, so fclExp is missing and totExp does not count the expenses twice.
+//
+// Freddie's loan sequence numbers do not overlap with Fannie's loan ids.
+//
+// The data is available at https://www.freddiemac.com/research/datasets/sf-loanlevel-dataset.
+package freddie
+
+import (
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/fannie/raw"
+	"io"
+	"strings"
+)
+
+// Agency is the value of the agency field for Freddie loans
+const Agency = "FHLMC"
+
+// monthly and origination file name prefixes
+var prefixes = map[string]string{
+	"historical_data_time_": "historical_data_",
+	"sample_svcg_":          "sample_orig_",
+}
+
+// IsMonthly returns true if fileName is a Freddie monthly performance file.
+func IsMonthly(fileName string) bool {
+	base := raw.BaseName(fileName)
+	for monthly := range prefixes {
+		if strings.HasPrefix(base, monthly) {
+			return true
+		}
+	}
+	return false
+}
+
+// origName returns the name of the origination file corresponding to the monthly file.
+func origName(monthlyName string) string {
+	for monthly, orig := range prefixes {
+		if ind := strings.LastIndex(monthlyName, monthly); ind >= 0 {
+			return monthlyName[:ind] + orig + monthlyName[ind+len(monthly):]
+		}
+	}
+	return monthlyName
+}
+
+// Open opens the monthly file joined to its origination file.  The data is presented in the Fannie sf2020 layout.
+// The origination file must be in the same directory (or .zip archive) and have the same compression.
+func Open(source string) (io.ReadSeekCloser, error) {
+	return raw.NewJoin(origName(source), source, spec)
+}
+
+// LoadRaw loads the monthly file sourceFile into table.
+func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chutils.Connect) error {
+	return raw.Load(sourceFile, table, "sf2020", Agency, Open, create, nConcur, con)
+}
+
+// spec specifies the join of the origination and monthly files.  Later releases added columns at the end.
+var spec = &raw.JoinSpec{
+	StaticCols:  25,
+	MonthlyCols: 21,
+	StaticKey:   19,
+	MonthlyKey:  0,
+	StaticMap: map[int]int{
+		0:  23, // Credit Score -> fico
+		1:  14, // First Payment Date -> fpDt
+		2:  25, // First Time Homebuyer Flag -> firstTime
+		3:  18, // Maturity Date -> matDt
+		4:  31, // MSA -> msa
+		5:  33, // MI % -> mi
+		6:  28, // Number of Units -> units
+		7:  29, // Occupancy Status -> occ
+		8:  20, // Original CLTV -> cltv
+		9:  22, // Original DTI -> dti
+		10: 9,  // Original UPB -> opb
+		11: 19, // Original LTV -> ltv
+		12: 7,  // Original Interest Rate -> rate
+		13: 3,  // Channel -> channel
+		14: 35, // PPM Flag -> pPen
+		15: 34, // Amortization Type -> amType
+		16: 30, // Property State -> state
+		17: 27, // Property Type -> propType
+		18: 32, // Postal Code -> zip3
+		19: 1,  // Loan Sequence Number -> lnId
+		20: 26, // Loan Purpose -> purpose
+		21: 12, // Original Loan Term -> term
+		22: 21, // Number of Borrowers -> numBorr
+		23: 4,  // Seller Name -> seller
+		24: 5,  // Servicer Name -> servicer
+		25: 86, // Super Conforming Flag -> sConform
+		27: 78, // Program Indicator -> program
+		29: 85, // Property Valuation Method -> valMthd
+		30: 36, // Interest Only Indicator -> io
+		31: 42, // MI Cancellation Indicator
+	},
+	MonthlyMap: map[int]int{
+		0:  1,   // Loan Sequence Number -> lnId
+		1:  2,   // Monthly Reporting Period -> month
+		2:  11,  // Current Actual UPB -> upb
+		3:  39,  // Current Loan Delinquency Status -> dqStat
+		4:  15,  // Loan Age -> age
+		5:  16,  // Remaining Months to Legal Maturity -> rTermLgl
+		7:  41,  // Modification Flag -> mod
+		8:  43,  // Zero Balance Code -> zb
+		9:  44,  // Zero Balance Effective Date -> zbDt
+		10: 8,   // Current Interest Rate -> curRate
+		11: 62,  // Current Deferred UPB -> nonIntUpb
+		12: 50,  // Due Date of Last Paid Installment -> lpDt
+		13: 59,  // MI Recoveries -> fclProMi
+		14: 58,  // Net Sales Proceeds -> fclProNet
+		15: 61,  // Non MI Recoveries -> fclProOth
+		17: 55,  // Legal Costs -> fclLExp
+		18: 54,  // Maintenance and Preservation Costs -> fclPExp
+		19: 57,  // Taxes and Insurance -> fclTaxes
+		20: 56,  // Miscellaneous Expenses -> fclMExp
+		26: 45,  // Zero Balance Removal UPB -> zbUpb
+		27: 84,  // Delinquent Accrued Interest
+		29: 101, // Borrower Assistance Status Code -> bap
+	},
+	Fix: fix,
+}
+
+// dates are the sf2020 columns that are dates.
+var dates = []int{2, 14, 18, 44, 50}
+
+// valMthds maps the Freddie property valuation method codes to Fannie's
+var valMthds = map[string]string{"1": "W", "2": "A", "3": "O", "4": "P", "9": ""}
+
+// programs maps the Freddie program indicator codes to Fannie's
+var programs = map[string]string{"H": "H", "F": "F", "R": "R", "9": "9"}
+
+// fix converts the Freddie formats and codes to Fannie's.
+func fix(out []string) {
+	for _, ind := range dates {
+		if d := out[ind]; len(d) == 6 {
+			out[ind] = d[4:] + d[:4]
+		}
+	}
+	if len(out[32]) > 3 {
+		out[32] = out[32][:3]
+	}
+	if len(out[39]) == 1 && out[39][0] >= '0' && out[39][0] <= '9' {
+		out[39] = "0" + out[39]
+	}
+	if out[26] == "N" {
+		out[26] = "U"
+	}
+	if v, ok := valMthds[out[85]]; ok {
+		out[85] = v
+	}
+	if out[78] != "" {
+		out[78] = programs[out[78]]
+	}
+	for _, ind := range []int{41, 86} {
+		if out[ind] == "" {
+			out[ind] = "N"
+		}
+	}
+}
@@ -0,0 +1,119 @@
+package freddie
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFix(t *testing.T) {
+	tests := []struct {
+		name string
+		in   map[int]string
+		want map[int]string
+	}{
+		{"dates", map[int]string{2: "202003", 14: "201901", 18: "204812", 44: "", 50: "20200"},
+			map[int]string{2: "032020", 14: "012019", 18: "122048", 44: "", 50: "20200"}},
+		{"zip3", map[int]string{32: "75200"}, map[int]string{32: "752"}},
+		{"dqStat", map[int]string{39: "3"}, map[int]string{39: "03"}},
+		{"dqStat RA", map[int]string{39: "RA"}, map[int]string{39: "RA"}},
+		{"purpose", map[int]string{26: "N"}, map[int]string{26: "U"}},
+		{"purpose cash-out", map[int]string{26: "C"}, map[int]string{26: "C"}},
+		{"valMthd", map[int]string{85: "1"}, map[int]string{85: "W"}},
+		{"valMthd missing", map[int]string{85: "9"}, map[int]string{85: ""}},
+		{"program", map[int]string{78: "H"}, map[int]string{78: "H"}},
+		{"program unknown", map[int]string{78: "Z"}, map[int]string{78: ""}},
+		{"mod and sConform blank", map[int]string{}, map[int]string{41: "N", 86: "N"}},
+		{"mod and sConform", map[int]string{41: "Y", 86: "Y"}, map[int]string{41: "Y", 86: "Y"}},
+	}
+	for _, tt := range tests {
+		out := make([]string, 108)
+		for ind, v := range tt.in {
+			out[ind] = v
+		}
+		fix(out)
+		for ind, v := range tt.want {
+			if out[ind] != v {
+				t.Errorf("%s: column %d = %q, want %q", tt.name, ind, out[ind], v)
+			}
+		}
+	}
+}
+
+func TestOrigName(t *testing.T) {
+	tests := []struct {
+		name    string
+		monthly bool
+		orig    string
+	}{
+		{"/data/historical_data_time_2020Q1.txt", true, "/data/historical_data_2020Q1.txt"},
+		{"/data/sample_svcg_2019.txt", true, "/data/sample_orig_2019.txt"},
+		{"/data/2020Q1.zip/historical_data_time_2020Q1.txt", true, "/data/2020Q1.zip/historical_data_2020Q1.txt"},
+		{"/data/historical_data_2020Q1.txt", false, "/data/historical_data_2020Q1.txt"},
+		{"/data/2020Q1.csv", false, "/data/2020Q1.csv"},
+	}
+	for _, tt := range tests {
+		if got := IsMonthly(tt.name); got != tt.monthly {
+			t.Errorf("IsMonthly(%s) = %v, want %v", tt.name, got, tt.monthly)
+		}
+		if got := origName(tt.name); got != tt.orig {
+			t.Errorf("origName(%s) = %s, want %s", tt.name, got, tt.orig)
+		}
+	}
+}
+
+// TestOpen joins an origination row to a monthly row with foreclosure expenses and checks the sf2020 columns.
+func TestOpen(t *testing.T) {
+	dir := t.TempDir()
+	orig := make([]string, 32)
+	orig[0], orig[1], orig[18], orig[19], orig[20], orig[27] = "750", "202002", "75201", "F20Q10000001", "N", "H"
+	monthly := make([]string, 32)
+	monthly[0], monthly[1], monthly[2], monthly[3] = "F20Q10000001", "202203", "0", "6"
+	monthly[16], monthly[17], monthly[18], monthly[19], monthly[20] = "-2000", "-100", "-200", "-300", "-400"
+	files := map[string]string{
+		"historical_data_2020Q1.txt":      strings.Join(orig, "|") + "\n",
+		"historical_data_time_2020Q1.txt": strings.Join(monthly, "|") + "\n",
+	}
+	for name, data := range files {
+		if e := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	rdr, err := Open(filepath.Join(dir, "historical_data_time_2020Q1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rdr.Close() }()
+	b, err := io.ReadAll(rdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := strings.Split(strings.TrimRight(string(b), "\n"), "|")
+	if len(out) != 108 {
+		t.Fatalf("got %d columns, want 108", len(out))
+	}
+
+	want := map[int]string{
+		1:  "F20Q10000001", // lnId
+		2:  "032022",       // month
+		14: "022020",       // fpDt
+		23: "750",          // fico
+		26: "U",            // purpose
+		32: "752",          // zip3
+		39: "06",           // dqStat
+		53: "",             // fclExp is not loaded
+		54: "-200",         // fclPExp
+		55: "-100",         // fclLExp
+		56: "-400",         // fclMExp
+		57: "-300",         // fclTaxes
+		78: "H",            // program
+	}
+	for ind, v := range want {
+		if out[ind] != v {
+			t.Errorf("column %d = %q, want %q", ind, out[ind], v)
+		}
+	}
+}
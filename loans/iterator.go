@@ -0,0 +1,216 @@
+package loans
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"reflect"
+	"strings"
+)
+
+// Filter selects the loans to read.  Empty fields don't filter.
+type Filter struct {
+	FromVintage string   // FromVintage is the first vintage to read, e.g. 2010Q1
+	ToVintage   string   // ToVintage is the last vintage to read
+	States      []string // States are the states to read
+	LnIDs       []string // LnIDs are the loans to read
+	Buckets     []int32  // Buckets are the buckets to read
+}
+
+// where returns the WHERE condition of f.  It is empty if f doesn't filter.
+func (f *Filter) where() string {
+	if f == nil {
+		return ""
+	}
+	conds := make([]string, 0)
+	if f.FromVintage != "" {
+		conds = append(conds, fmt.Sprintf("vintage >= '%s'", f.FromVintage))
+	}
+	if f.ToVintage != "" {
+		conds = append(conds, fmt.Sprintf("vintage <= '%s'", f.ToVintage))
+	}
+	if len(f.States) > 0 {
+		conds = append(conds, fmt.Sprintf("state IN (%s)", quote(f.States)))
+	}
+	if len(f.LnIDs) > 0 {
+		conds = append(conds, fmt.Sprintf("lnId IN (%s)", quote(f.LnIDs)))
+	}
+	if len(f.Buckets) > 0 {
+		bkts := make([]string, 0, len(f.Buckets))
+		for _, b := range f.Buckets {
+			bkts = append(bkts, fmt.Sprintf("%d", b))
+		}
+		conds = append(conds, fmt.Sprintf("bucket IN (%s)", strings.Join(bkts, ", ")))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// quote returns the comma-separated list of vals as quoted strings
+func quote(vals []string) string {
+	q := make([]string, 0, len(vals))
+	for _, v := range vals {
+		q = append(q, "'"+strings.ReplaceAll(v, "'", "\\'")+"'")
+	}
+	return strings.Join(q, ", ")
+}
+
+// target is where a column goes in a Loan
+type target struct {
+	col   string       // col is the column of the table
+	field int          // field is the index of the Loan field
+	sub   int          // sub is the index of the field of the nest struct. -1 if the column isn't in a nest.
+	typ   reflect.Type // typ is the Go type of a value of the column
+}
+
+// targets returns the columns read into a Loan, in the order of the fields of Loan
+func targets() []*target {
+	tgts := make([]*target, 0)
+	lt := reflect.TypeOf(Loan{})
+	for ind := 0; ind < lt.NumField(); ind++ {
+		f := lt.Field(ind)
+		if f.Type.Kind() == reflect.Slice && f.Type.Elem().Kind() == reflect.Struct {
+			for sub := 0; sub < f.Type.Elem().NumField(); sub++ {
+				sf := f.Type.Elem().Field(sub)
+				tgts = append(tgts, &target{col: f.Tag.Get("ch") + "." + sf.Tag.Get("ch"), field: ind, sub: sub,
+					typ: sf.Type})
+			}
+			continue
+		}
+		tgts = append(tgts, &target{col: f.Tag.Get("ch"), field: ind, sub: -1, typ: f.Type})
+	}
+	return tgts
+}
+
+// Iterator streams the loans of a table.  It is used like sql.Rows.
+type Iterator struct {
+	rows *sql.Rows
+	tgts []*target
+	loan *Loan
+	err  error
+}
+
+// NewIterator returns an iterator over the loans of table, built by package collapse, that pass flt (all loans
+// if flt is nil).  It is an error if table is missing any column of Loan.
+func NewIterator(table string, flt *Filter, con *chutils.Connect) (*Iterator, error) {
+	tgts := targets()
+	if e := checkColumns(table, tgts, con); e != nil {
+		return nil, e
+	}
+	cols := make([]string, 0, len(tgts))
+	for _, t := range tgts {
+		cols = append(cols, "`"+t.col+"`")
+	}
+	qry := fmt.Sprintf("SELECT %s FROM %s", strings.Join(cols, ", "), table)
+	if where := flt.where(); where != "" {
+		qry += " WHERE " + where
+	}
+	rows, err := con.Query(qry)
+	if err != nil {
+		return nil, err
+	}
+	return &Iterator{rows: rows, tgts: tgts}, nil
+}
+
+// Next reads the next loan.  It returns false when there are no more loans or there is an error.
+func (it *Iterator) Next() bool {
+	if it.err != nil || !it.rows.Next() {
+		return false
+	}
+	ln := &Loan{}
+	lv := reflect.ValueOf(ln).Elem()
+	dest := make([]interface{}, len(it.tgts))
+	for ind, t := range it.tgts {
+		if t.sub < 0 {
+			dest[ind] = lv.Field(t.field).Addr().Interface()
+			continue
+		}
+		dest[ind] = reflect.New(reflect.SliceOf(t.typ)).Interface()
+	}
+	if it.err = it.rows.Scan(dest...); it.err != nil {
+		return false
+	}
+
+	// the columns of a nest are arrays of the same length -- these become a slice of structs
+	for ind, t := range it.tgts {
+		if t.sub < 0 {
+			trim(lv.Field(t.field))
+			continue
+		}
+		arr := reflect.ValueOf(dest[ind]).Elem()
+		nest := lv.Field(t.field)
+		if nest.Len() == 0 {
+			nest.Set(reflect.MakeSlice(nest.Type(), arr.Len(), arr.Len()))
+		}
+		if arr.Len() != nest.Len() {
+			it.err = fmt.Errorf("loan %s: %s has %d elements, expected %d", ln.LnID, t.col, arr.Len(), nest.Len())
+			return false
+		}
+		for j := 0; j < arr.Len(); j++ {
+			elem := nest.Index(j).Field(t.sub)
+			elem.Set(arr.Index(j))
+			trim(elem)
+		}
+	}
+	it.loan = ln
+	return true
+}
+
+// Loan returns the loan read by Next
+func (it *Iterator) Loan() *Loan {
+	return it.loan
+}
+
+// Err returns the error, if any, that stopped the iteration
+func (it *Iterator) Err() error {
+	if it.err != nil {
+		return it.err
+	}
+	return it.rows.Err()
+}
+
+// Close closes the iterator
+func (it *Iterator) Close() error {
+	return it.rows.Close()
+}
+
+// checkColumns checks that table has the columns of tgts
+func checkColumns(table string, tgts []*target, con *chutils.Connect) error {
+	rows, err := con.Query(fmt.Sprintf("DESCRIBE %s", table))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	have := make(map[string]bool)
+	for rows.Next() {
+		var name, chType, defaultType, defaultExpression, comment, codec, ttl string
+		if e := rows.Scan(&name, &chType, &defaultType, &defaultExpression, &comment, &codec, &ttl); e != nil {
+			return e
+		}
+		have[name] = true
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	missing := make([]string, 0)
+	for _, t := range tgts {
+		if !have[t.col] {
+			missing = append(missing, t.col)
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("table %s is missing columns: %s", table, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// trim removes the null padding of FixedString values from v if it is a string or a slice of strings
+func trim(v reflect.Value) {
+	switch {
+	case v.Kind() == reflect.String:
+		v.SetString(strings.TrimRight(v.String(), "\x00"))
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.String:
+		for ind := 0; ind < v.Len(); ind++ {
+			trim(v.Index(ind))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package loans
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFilterWhere(t *testing.T) {
+	tests := []struct {
+		name string
+		flt  *Filter
+		want string
+	}{
+		{"nil", nil, ""},
+		{"empty", &Filter{}, ""},
+		{"vintages", &Filter{FromVintage: "2010Q1", ToVintage: "2012Q4"},
+			"vintage >= '2010Q1' AND vintage <= '2012Q4'"},
+		{"states", &Filter{States: []string{"TX", "CA"}}, "state IN ('TX', 'CA')"},
+		{"quoted", &Filter{LnIDs: []string{"1", "o'x"}}, `lnId IN ('1', 'o\'x')`},
+		{"buckets", &Filter{Buckets: []int32{0, 7}}, "bucket IN (0, 7)"},
+		{"all", &Filter{FromVintage: "2010Q1", States: []string{"TX"}, LnIDs: []string{"1"}, Buckets: []int32{3}},
+			"vintage >= '2010Q1' AND state IN ('TX') AND lnId IN ('1') AND bucket IN (3)"},
+	}
+	for _, tt := range tests {
+		if got := tt.flt.where(); got != tt.want {
+			t.Errorf("%s: where() = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestTargets(t *testing.T) {
+	tgts := targets()
+	byCol := make(map[string]*target)
+	for _, tg := range tgts {
+		if tg.col == "" || tg.col[len(tg.col)-1] == '.' {
+			t.Errorf("field %d has no ch tag", tg.field)
+		}
+		if _, ok := byCol[tg.col]; ok {
+			t.Errorf("column %s is read twice", tg.col)
+		}
+		byCol[tg.col] = tg
+	}
+	lt := reflect.TypeOf(Loan{})
+	tests := []struct {
+		col   string
+		field string
+		sub   int
+		typ   reflect.Type
+	}{
+		{"lnId", "LnID", -1, reflect.TypeOf("")},
+		{"bucket", "Bucket", -1, reflect.TypeOf(int32(0))},
+		{"allFail", "AllFail", -1, reflect.TypeOf([]string{})},
+		{"monthly.month", "Months", 0, reflect.TypeOf(time.Time{})},
+		{"monthly.upb", "Months", 1, reflect.TypeOf(float32(0))},
+		{"payHist.phDq", "PayHist", 1, reflect.TypeOf(int32(0))},
+		{"dqHist.dhMonth", "DqHist", 0, reflect.TypeOf(time.Time{})},
+		{"qa.field", "QA", 0, reflect.TypeOf("")},
+	}
+	for _, tt := range tests {
+		tg, ok := byCol[tt.col]
+		if !ok {
+			t.Errorf("no target for %s", tt.col)
+			continue
+		}
+		if lt.Field(tg.field).Name != tt.field || tg.sub != tt.sub || tg.typ != tt.typ {
+			t.Errorf("%s: got field %s sub %d type %v, want %s %d %v", tt.col, lt.Field(tg.field).Name, tg.sub, tg.typ,
+				tt.field, tt.sub, tt.typ)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Package loans reads the table built by package collapse into Go structs.
+//
+// A Loan has the static fields of the table and the nests as slices of structs: monthly as Month, qa as QA, payHist
+// as PayHist and dqHist as DqHist.  The ch tag of each field is the column it is read from (for the nests, the column
+// within the nest).  The fields loaded by -full are not included.
+//
+// Loans are streamed from ClickHouse by an Iterator, which may filter by vintage, state, lnId and bucket:
+//
+//	it, err := loans.NewIterator("mtg.fannie", &loans.Filter{FromVintage: "2010Q1", States: []string{"TX"}}, con)
+//	...
+//	defer func() { _ = it.Close() }()
+//	for it.Next() {
+//	    ln := it.Loan()
+//	    ...
+//	}
+//	if it.Err() != nil { ... }
+package loans
+
+import "time"
+
+// Month is a month of the monthly nest
+type Month struct {
+	Month     time.Time `ch:"month"`     // Month is the month of data
+	Upb       float32   `ch:"upb"`       // Upb is the unpaid balance, missing=-1
+	Dq        int32     `ch:"dq"`        // Dq is the # of months delinquent
+	Servicer  string    `ch:"servicer"`  // Servicer is the name of the servicer, missing=unknown
+	CurRate   float32   `ch:"curRate"`   // CurRate is the current note rate, missing=-1
+	Age       int32     `ch:"age"`       // Age is the loan age based on the origination date, missing=-1
+	AgeFpDt   int64     `ch:"ageFpDt"`   // AgeFpDt is the age based on fpDt, missing=-1000
+	RTermLgl  int32     `ch:"rTermLgl"`  // RTermLgl is the remaining legal term, missing=-1
+	RTermAct  int32     `ch:"rTermAct"`  // RTermAct is the remaining actual term, missing=-1
+	DqStat    string    `ch:"dqStat"`    // DqStat is the DQ status code: 0-99 months, missing=!
+	Mod       string    `ch:"mod"`       // Mod is the modification flag: Y, N, missing=X
+	Zb        string    `ch:"zb"`        // Zb is the zero balance code, 00=none
+	IoRem     int32     `ch:"ioRem"`     // IoRem is the # of IO months remaining, missing=-1
+	Bap       string    `ch:"bap"`       // Bap is the borrower assistance plan: F, R, T, O, N, missing=X
+	Program   string    `ch:"program"`   // Program is the fannie special eligibility program: F, H, R, O, 7, 9, missing=X
+	NonIntUpb float32   `ch:"nonIntUpb"` // NonIntUpb is the non-interest bearing UPB, missing=-1
+	FrgvUpb   float32   `ch:"frgvUpb"`   // FrgvUpb is the forgiven UPB, missing=-1
+	TotPrin   float32   `ch:"totPrin"`   // TotPrin is the change in upb
+	ServAct   string    `ch:"servAct"`   // ServAct is the servicing activity flag: Y, N, missing=X
+	SchedPrin float32   `ch:"schedPrin"` // SchedPrin is the scheduled principal, missing=-1
+	Curtail   float32   `ch:"curtail"`   // Curtail is the curtailment, missing=-1
+	Smm       float32   `ch:"smm"`       // Smm is the single monthly mortality, missing=-1
+	HpiVal    float32   `ch:"hpiVal"`    // HpiVal is the property value indexed by the HPI, missing=-1
+	ELtv      float32   `ch:"eLtv"`      // ELtv is the mark-to-market LTV, missing=-1
+	Incentive float32   `ch:"incentive"` // Incentive is the refinance incentive, missing=-99
+	MatDt     time.Time `ch:"matDt"`     // MatDt is the maturity date, missing=1970/1/1
+}
+
+// PayHist is a month of the payHist nest
+type PayHist struct {
+	Month time.Time `ch:"phMonth"` // Month is the month of pay history
+	Dq    int32     `ch:"phDq"`    // Dq is the # of months delinquent from pay history, missing=-1
+}
+
+// DqHist is a month of the dqHist nest
+type DqHist struct {
+	Month time.Time `ch:"dhMonth"` // Month is the month of dq history
+	Dq    int32     `ch:"dhDq"`    // Dq is the # of months delinquent, back-filled from pay history
+}
+
+// QA is an entry of the qa nest
+type QA struct {
+	Field   string `ch:"field"`   // Field is the field name, ts:<check> for longitudinal checks
+	CntFail int32  `ch:"cntFail"` // CntFail is the # of months Field failed qa
+}
+
+// Loan is a row of the table built by package collapse
+type Loan struct {
+	LnID         string    `ch:"lnId"`         // LnID is the loan ID
+	Months       []Month   `ch:"monthly"`      // Months is the monthly nest
+	Channel      string    `ch:"channel"`      // Channel is the acquisition channel: B, R, C, missing=X
+	Seller       string    `ch:"seller"`       // Seller is the name of the seller, missing=unknown
+	Dti          float32   `ch:"dti"`          // Dti is the dti at origination, missing=-1
+	Rate         float32   `ch:"rate"`         // Rate is the note rate at origination, missing=-1
+	Opb          float32   `ch:"opb"`          // Opb is the balance at origination, missing=-1
+	Term         float32   `ch:"term"`         // Term is the loan term at origination, missing=-1
+	OrigDt       time.Time `ch:"origDt"`       // OrigDt is the origination date, missing=1970/1/1
+	FpDt         time.Time `ch:"fpDt"`         // FpDt is the first payment date, missing=1970/1/1
+	Ltv          float32   `ch:"ltv"`          // Ltv is the ltv at origination, missing=-1
+	Cltv         float32   `ch:"cltv"`         // Cltv is the cltv at origination, missing=-1
+	NumBorr      int32     `ch:"numBorr"`      // NumBorr is the # of borrowers, missing=-1
+	Fico         int32     `ch:"fico"`         // Fico is the fico at origination, missing=-1
+	CoFico       int32     `ch:"coFico"`       // CoFico is the coborrower fico at origination, missing=-1
+	FirstTime    string    `ch:"firstTime"`    // FirstTime is the first time homebuyer flag: Y, N, missing=X
+	Purpose      string    `ch:"purpose"`      // Purpose is the loan purpose: P, C, U, R, missing=X
+	PropType     string    `ch:"propType"`     // PropType is the property type: SF, CO, PU, CP, MH, missing=XX
+	Units        float32   `ch:"units"`        // Units is the # of units in the property, missing=-1
+	Occ          string    `ch:"occ"`          // Occ is the occupancy: P, S, I, missing=X
+	State        string    `ch:"state"`        // State is the property state, missing=XX
+	Msa          string    `ch:"msa"`          // Msa is the msa/division code, missing=XXXXX
+	MsaOrig      string    `ch:"msaOrig"`      // MsaOrig is the msa as reported, missing=XXXXX
+	Zip3         string    `ch:"zip3"`         // Zip3 is the 3-digit zip, missing=XXX
+	Mi           float32   `ch:"mi"`           // Mi is the mi percentage, missing=-1
+	AmType       string    `ch:"amType"`       // AmType is the amortization type: FRM, ARM, missing=XXX
+	PPen         string    `ch:"pPen"`         // PPen is the prepay penalty flag: Y, N, missing=X
+	Io           string    `ch:"io"`           // Io is the IO flag: Y, N, missing=X
+	IoDt         time.Time `ch:"ioDt"`         // IoDt is the month the IO loan starts amortizing, missing=1970/1/1
+	ZbDt         time.Time `ch:"zbDt"`         // ZbDt is the zero balance date, missing=1970/1/1
+	ZbUpb        float32   `ch:"zbUpb"`        // ZbUpb is the UPB just prior to zero balance, missing=-1
+	LpDt         time.Time `ch:"lpDt"`         // LpDt is the last pay date, missing=1970/1/1
+	FclDt        time.Time `ch:"fclDt"`        // FclDt is the foreclosure date, missing=1970/1/1
+	DispDt       time.Time `ch:"dispDt"`       // DispDt is the disposition date, missing=1970/1/1
+	FclExp       float32   `ch:"fclExp"`       // FclExp is the total foreclosure expenses
+	FclPExp      float32   `ch:"fclPExp"`      // FclPExp is the property preservation expenses
+	FclLExp      float32   `ch:"fclLExp"`      // FclLExp is the recovery legal expenses
+	FclMExp      float32   `ch:"fclMExp"`      // FclMExp is the misc expenses, missing=-1
+	FclTaxes     float32   `ch:"fclTaxes"`     // FclTaxes is the property taxes and insurance
+	FclProNet    float32   `ch:"fclProNet"`    // FclProNet is the net sale proceeds
+	FclProMi     float32   `ch:"fclProMi"`     // FclProMi is the credit enhancement proceeds
+	FclProMw     float32   `ch:"fclProMw"`     // FclProMw is the make whole proceeds
+	FclProOth    float32   `ch:"fclProOth"`    // FclProOth is the other proceeds
+	FclWriteOff  float32   `ch:"fclWriteOff"`  // FclWriteOff is the principal writeoff, missing=-1
+	MiType       string    `ch:"miType"`       // MiType is the mi type: 1, 2, 3, 0, missing=X
+	Relo         string    `ch:"relo"`         // Relo is the relocation mortgage flag: Y, N, missing=X
+	ValMthd      string    `ch:"valMthd"`      // ValMthd is the property value method: A, P, R, W, O, missing=X
+	SConform     string    `ch:"sConform"`     // SConform is the super conforming flag: Y, N, missing=X
+	Hltv         string    `ch:"hltv"`         // Hltv is the high LTV refi flag, missing=X
+	ReprchMw     string    `ch:"reprchMw"`     // ReprchMw is the repurchase make whole flag: Y, N, missing=X
+	AltRes       string    `ch:"altRes"`       // AltRes is the payment deferral type: P, C, D, 7, 9, missing=X
+	AltResCnt    int32     `ch:"altResCnt"`    // AltResCnt is the # of deferrals, missing=-1
+	TotDefrl     float32   `ch:"totDefrl"`     // TotDefrl is the total amount deferred, missing=-1
+	File         string    `ch:"file"`         // File is the source file
+	Vintage      string    `ch:"vintage"`      // Vintage is the vintage (from fpDt), e.g. 2010Q1
+	PropVal      float32   `ch:"propVal"`      // PropVal is the property value at origination
+	Sato         float32   `ch:"sato"`         // Sato is the spread at origination, missing=-99
+	Harp         string    `ch:"harp"`         // Harp is Y if the loan is HARP
+	Standard     string    `ch:"standard"`     // Standard is Y if the loan is standard u/w process
+	Agency       string    `ch:"agency"`       // Agency is FNMA or FHLMC
+	NsDoc        string    `ch:"nsDoc"`        // NsDoc is the non-standard documentation flag: Y, N, missing=X
+	NsUw         string    `ch:"nsUw"`         // NsUw is the non-standard underwriting flag: Y, N, missing=X
+	GGuar        string    `ch:"gGuar"`        // GGuar is the government guaranteed flag: Y, N, missing=X
+	NegAm        string    `ch:"negAm"`        // NegAm is the negative amortization flag: Y, N, missing=X
+	RefPool      string    `ch:"refPool"`      // RefPool is the CRT reference pool ID, none=not in CRT file
+	DealName     string    `ch:"dealName"`     // DealName is the CRT deal name, none=not in CRT file
+	ModLossCum   float32   `ch:"modLossCum"`   // ModLossCum is the CRT cumulative modification loss, missing=-1
+	CeLossCum    float32   `ch:"ceLossCum"`    // CeLossCum is the CRT cumulative credit event gain/loss, missing=-1
+	DqAccrInt    float32   `ch:"dqAccrInt"`    // DqAccrInt is the delinquent accrued interest, 0=not liquidated
+	TotExp       float32   `ch:"totExp"`       // TotExp is the total expenses, 0=not liquidated
+	TotPro       float32   `ch:"totPro"`       // TotPro is the total proceeds, 0=not liquidated
+	NetLoss      float32   `ch:"netLoss"`      // NetLoss is the net loss, 0=not liquidated
+	Severity     float32   `ch:"severity"`     // Severity is the loss severity, 0=not liquidated
+	Dq30Dt       time.Time `ch:"dq30Dt"`       // Dq30Dt is the first month 30+ days delinquent, missing=1970/1/1
+	Dq60Dt       time.Time `ch:"dq60Dt"`       // Dq60Dt is the first month 60+ days delinquent, missing=1970/1/1
+	Dq90Dt       time.Time `ch:"dq90Dt"`       // Dq90Dt is the first month 90+ days delinquent, missing=1970/1/1
+	Dq180Dt      time.Time `ch:"dq180Dt"`      // Dq180Dt is the first month 180+ days delinquent, missing=1970/1/1
+	ModDt        time.Time `ch:"modDt"`        // ModDt is the first month modified, missing=1970/1/1
+	FbDt         time.Time `ch:"fbDt"`         // FbDt is the first month in forbearance, missing=1970/1/1
+	ZbMonth      time.Time `ch:"zbMonth"`      // ZbMonth is the first month with a zero balance code, missing=1970/1/1
+	ZbCode       string    `ch:"zbCode"`       // ZbCode is the zero balance code of ZbMonth, 00=not zero balance
+	Dq30ToLiq    int32     `ch:"dq30ToLiq"`    // Dq30ToLiq is the # of months from Dq30Dt to ZbMonth, missing=-1
+	Dq60ToLiq    int32     `ch:"dq60ToLiq"`    // Dq60ToLiq is the # of months from Dq60Dt to ZbMonth, missing=-1
+	Dq90ToLiq    int32     `ch:"dq90ToLiq"`    // Dq90ToLiq is the # of months from Dq90Dt to ZbMonth, missing=-1
+	Dq180ToLiq   int32     `ch:"dq180ToLiq"`   // Dq180ToLiq is the # of months from Dq180Dt to ZbMonth, missing=-1
+	ModToLiq     int32     `ch:"modToLiq"`     // ModToLiq is the # of months from ModDt to ZbMonth, missing=-1
+	FbToLiq      int32     `ch:"fbToLiq"`      // FbToLiq is the # of months from FbDt to ZbMonth, missing=-1
+	CurtailTotal float32   `ch:"curtailTotal"` // CurtailTotal is the total of curtail
+	Bucket       int32     `ch:"bucket"`       // Bucket is the loan bucket, calculated from lnId
+	HarpLnID     string    `ch:"harpLnId"`     // HarpLnID is the HARP loan this loan was refinanced to
+	PreHarpID    string    `ch:"preHarpId"`    // PreHarpID is the loan this HARP loan was refinanced from
+	PayHist      []PayHist `ch:"payHist"`      // PayHist is the payHist nest
+	DqHist       []DqHist  `ch:"dqHist"`       // DqHist is the dqHist nest
+	QA           []QA      `ch:"qa"`           // QA is the qa nest
+	AllFail      []string  `ch:"allFail"`      // AllFail are the fields that failed QA all months
+}
@@ -0,0 +1,164 @@
+// Package manifest maintains a ClickHouse table that records each source file loaded into an output table.
+//
+// A row is written when a file is started and another when it is complete.  The latest row for the file
+// gives its status:
+//   - started.  The load began but did not finish.  Some of its loans may be in the output table.
+//   - complete.  The file is fully loaded.
+//
+// Files are identified by their checksum, so a file is recognized even if it has been moved or renamed.  Members of
+// .zip archives are identified by the CRC-32 the archive records and by their name (archive + member), since
+// CRC-32 is too weak to identify a member alone.
+package manifest
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/fannie/raw"
+	"io"
+	"os"
+	"time"
+)
+
+// Status values
+const (
+	Started  = "started"
+	Complete = "complete"
+)
+
+// Entry is a row of the manifest table
+type Entry struct {
+	Table    string    // Table is the output table the file is loaded into
+	File     string    // File is the source file, as recorded in the file field of Table
+	Size     int64     // Size is the # of bytes in the file as stored (compressed, if it is) or in the .zip member
+	Checksum string    // Checksum is the sha256 hash of the file as stored or crc32:<hex> of the .zip member
+	Rows     int64     // Rows is the # of rows in the file
+	Loans    int64     // Loans is the # of distinct loans in the file
+	Release  string    // Release is a user-supplied tag for the data release
+	Start    time.Time // Start is the time the load started
+	Finish   time.Time // Finish is the time the load finished.  Equal to Start if it has not finished
+	Status   string    // Status is Started or Complete
+}
+
+// NewEntry creates an entry for sourceFile, which is to be loaded into table.  The size and checksum are
+// calculated on the file as stored, so a compressed file is not decompressed just to hash it.  For a .zip member,
+// they are the uncompressed size and CRC-32 recorded in the archive.
+func NewEntry(sourceFile string, table string, release string) (*Entry, error) {
+	size, checksum, err := fingerprint(sourceFile)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &Entry{
+		Table:    table,
+		File:     sourceFile,
+		Size:     size,
+		Checksum: checksum,
+		Release:  release,
+		Start:    now,
+		Finish:   now,
+		Status:   Started,
+	}, nil
+}
+
+// fingerprint returns the size and checksum of sourceFile
+func fingerprint(sourceFile string) (size int64, checksum string, err error) {
+	if raw.IsMember(sourceFile) {
+		crc, sz, e := raw.MemberCRC(sourceFile)
+		if e != nil {
+			return 0, "", e
+		}
+		return sz, fmt.Sprintf("crc32:%08x", crc), nil
+	}
+	f, err := os.Open(sourceFile)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if size, err = io.Copy(h, f); err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Create creates the manifest table if it does not exist.
+func Create(manifest string, con *chutils.Connect) error {
+	_, err := con.Exec(fmt.Sprintf(createQry, manifest))
+	return err
+}
+
+// Reset removes all the entries for table.  This is called when table is re-created.
+func Reset(manifest string, table string, con *chutils.Connect) error {
+	qry := fmt.Sprintf("ALTER TABLE %s DELETE WHERE table = ? SETTINGS mutations_sync = 1", manifest)
+	_, err := con.Exec(qry, table)
+	return err
+}
+
+// Last returns the latest entry in manifest for a file with the same checksum loaded into the same table.  For a .zip
+// member, the entry must also be for the same archive and member.
+// It returns nil if there are none.
+func (e *Entry) Last(manifest string, con *chutils.Connect) (*Entry, error) {
+	where, args := "table = ? AND checksum = ?", []interface{}{e.Table, e.Checksum}
+	if raw.IsMember(e.File) {
+		where, args = where+" AND file = ?", append(args, e.File)
+	}
+	qry := fmt.Sprintf(`
+SELECT
+  argMax(file, finish),
+  argMax(status, finish),
+  max(finish)
+FROM %s
+WHERE %s
+GROUP BY table, checksum`, manifest, where)
+
+	last := &Entry{Table: e.Table, Checksum: e.Checksum}
+	err := con.QueryRow(qry, args...).Scan(&last.File, &last.Status, &last.Finish)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return last, nil
+}
+
+// Count populates the row and loan counts from the table the raw data is loaded into.
+func (e *Entry) Count(sourceTable string, con *chutils.Connect) error {
+	qry := fmt.Sprintf("SELECT toInt64(count(*)), toInt64(uniqExact(lnId)) FROM %s", sourceTable)
+	return con.QueryRow(qry).Scan(&e.Rows, &e.Loans)
+}
+
+// Write writes the entry to the manifest table.
+func (e *Entry) Write(manifest string, con *chutils.Connect) error {
+	qry := fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)", manifest)
+	_, err := con.Exec(qry, e.Table, e.File, e.Size, e.Checksum, e.Rows, e.Loans, e.Release, e.Start, e.Finish, e.Status)
+	return err
+}
+
+// Unload deletes the loans loaded from file from table.  This is used to back out a partially loaded file.
+func Unload(table string, file string, con *chutils.Connect) error {
+	qry := fmt.Sprintf("ALTER TABLE %s DELETE WHERE file = ? SETTINGS mutations_sync = 1", table)
+	_, err := con.Exec(qry, file)
+	return err
+}
+
+// createQry creates the manifest table.  There is a placeholder for the table name.
+const createQry = `
+CREATE TABLE IF NOT EXISTS %s (
+  table    String   COMMENT 'output table the file is loaded into',
+  file     String   COMMENT 'source file',
+  size     Int64    COMMENT '# of bytes in the file as stored or in the .zip member',
+  checksum String   COMMENT 'sha256 of the file as stored or crc32 of the .zip member',
+  rows     Int64    COMMENT '# of rows in the file',
+  loans    Int64    COMMENT '# of loans in the file',
+  release  String   COMMENT 'data release tag',
+  start    DateTime COMMENT 'time load started',
+  finish   DateTime COMMENT 'time load finished',
+  status   LowCardinality(String) COMMENT 'status: started, complete'
+) ENGINE=MergeTree()
+ORDER BY (table, checksum, finish)
+`
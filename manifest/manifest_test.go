@@ -0,0 +1,68 @@
+package manifest
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFingerprint(t *testing.T) {
+	dir := t.TempDir()
+	archive := filepath.Join(dir, "2020Q1.zip")
+	f, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, m := range []struct{ name, data string }{{"a.csv", "1|2|3\n"}, {"b.csv", "4|5|6|7\n"}} {
+		w, e := zw.Create(m.name)
+		if e != nil {
+			t.Fatal(e)
+		}
+		if _, e := w.Write([]byte(m.data)); e != nil {
+			t.Fatal(e)
+		}
+	}
+	if e := zw.Close(); e != nil {
+		t.Fatal(e)
+	}
+	if e := f.Close(); e != nil {
+		t.Fatal(e)
+	}
+	plain := filepath.Join(dir, "2020Q1.csv")
+	if e := os.WriteFile(plain, []byte("abc"), 0o644); e != nil {
+		t.Fatal(e)
+	}
+
+	tests := []struct {
+		source   string
+		size     int64
+		checksum string
+		wantErr  bool
+	}{
+		{archive + "/a.csv", 6, "crc32:", false},
+		{archive + "/b.csv", 8, "crc32:", false},
+		{archive + "/c.csv", 0, "", true},
+		{plain, 3, "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad", false},
+		{filepath.Join(dir, "missing.csv"), 0, "", true},
+	}
+	seen := make(map[string]bool)
+	for _, tt := range tests {
+		size, checksum, err := fingerprint(tt.source)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fingerprint(%s) error = %v, wantErr %v", tt.source, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if size != tt.size || len(checksum) < len(tt.checksum) || checksum[:len(tt.checksum)] != tt.checksum {
+			t.Errorf("fingerprint(%s) = %d, %s, want %d, %s", tt.source, size, checksum, tt.size, tt.checksum)
+		}
+		if seen[checksum] {
+			t.Errorf("fingerprint(%s) = %s is not unique", tt.source, checksum)
+		}
+		seen[checksum] = true
+	}
+}
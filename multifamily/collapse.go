@@ -0,0 +1,151 @@
+package multifamily
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	s "github.com/invertedv/chutils/sql"
+	"github.com/invertedv/fannie/collapse"
+	"github.com/invertedv/fannie/raw"
+	"strings"
+)
+
+// GroupBy groups the table created by LoadRaw (which has one row per loan per month) to a table with one row per loan.
+// There are nested tables:
+//   - monthly.  These are values that change every month.
+//   - qa. The qa table.
+func GroupBy(sourceTable string, table string, create bool, con *chutils.Connect) error {
+	q := strings.Replace(qry, "sourceTable", sourceTable, 2)
+	q = strings.Replace(q, "<buckets>", fmt.Sprintf("%d", collapse.Buckets), 1)
+
+	// the query has placeholders for the missing values of fields of the form <fieldMissing>
+	for _, f := range TableDef.FieldDefs {
+		q = strings.Replace(q, fmt.Sprintf("<%sMissing>", f.Name), fmt.Sprintf("%v", f.Missing), -1)
+	}
+
+	rdr := s.NewReader(q, con)
+	defer func() { _ = rdr.Close() }()
+	if e := rdr.Init("lnId", chutils.MergeTree); e != nil {
+		return e
+	}
+	if create {
+		// nested fields
+		if e := rdr.TableSpec().Nest("monthly", "month", "mod"); e != nil {
+			return e
+		}
+		if e := rdr.TableSpec().Nest("qa", "field", "cntFail"); e != nil {
+			return e
+		}
+		// bring over descriptions
+		for _, f := range rdr.TableSpec().FieldDefs {
+			// FixedString and LowCardinality don't survive the groupArray
+			raw.CopySpecFrom(TableDef, f)
+			// added details for new fields
+			switch f.Name {
+			case "bucket":
+				f.Description = fmt.Sprintf("loan bucket: 0-%d", collapse.Buckets-1)
+			case "field":
+				f.Description = "field name"
+				f.ChSpec.Funcs = append(f.ChSpec.Funcs, chutils.OuterLowCardinality)
+			case "cntFail":
+				f.Description = "# of months field failed qa"
+			case "allFail":
+				f.Description = "fields that failed QA all months"
+				f.ChSpec.Funcs = append(f.ChSpec.Funcs, chutils.OuterLowCardinality)
+			}
+		}
+		if e := rdr.TableSpec().Create(con, table); e != nil {
+			return e
+		}
+	}
+	rdr.Name = table
+	rdr.Sql = strings.Replace(rdr.Sql, "LIMIT 10", "", 1)
+	return rdr.Insert()
+}
+
+// qry is the query that collapses the multiple rows per lnId to a single one.
+//
+// Static fields take the first non-missing value.  There is a placeholder for the # of buckets.
+// Note: the "LIMIT 10" makes the query run much faster for the Init() method.
+const qry = `
+WITH q AS (
+  SELECT lnId,
+    groupArray(grp) AS qa,
+    groupArray(n) AS nqa
+FROM (
+  SELECT
+     lnId,
+     arrayJoin(splitByChar(':', qa)) AS grp,
+     toInt32(count(*)) AS n
+  FROM sourceTable
+  WHERE grp != ''
+  GROUP BY lnId, grp)
+GROUP BY lnId),
+r AS (
+SELECT
+  lnId,
+  groupArray(toLastDayOfMonth(month)) AS month,
+  groupArray(payStat) AS payStat,
+  groupArray(upb) AS upb,
+  groupArray(curRate) AS curRate,
+  groupArray(occ) AS occ,
+  groupArray(curDscr) AS curDscr,
+  groupArray(zb) AS zb,
+  groupArray(pPenProv) AS pPenProv,
+  groupArray(mod) AS mod,
+
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(acqDt)) AS acqDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(noteDt)) AS noteDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(matDt)) AS matDt,
+  arrayExists(x->x!=<opbMissing>, groupArray(opb)) ? arrayFirst(x->x!=<opbMissing> ? 1 : 0, groupArray(opb)) : <opbMissing> AS opb,
+  arrayFirst(x->x!='<amTypeMissing>' ? 1 : 0, groupArray(amType)) = '' ? '<amTypeMissing>' : arrayFirst(x->x!='<amTypeMissing>' ? 1 : 0, groupArray(amType)) AS amType,
+  arrayExists(x->x!=<rateMissing>, groupArray(rate)) ? arrayFirst(x->x!=<rateMissing> ? 1 : 0, groupArray(rate)) : <rateMissing> AS rate,
+  arrayFirst(x->x!='<productMissing>' ? 1 : 0, groupArray(product)) = '' ? '<productMissing>' : arrayFirst(x->x!='<productMissing>' ? 1 : 0, groupArray(product)) AS product,
+  arrayExists(x->x!=<termMissing>, groupArray(term)) ? arrayFirst(x->x!=<termMissing> ? 1 : 0, groupArray(term)) : <termMissing> AS term,
+  arrayExists(x->x!=<ioTermMissing>, groupArray(ioTerm)) ? arrayFirst(x->x!=<ioTermMissing> ? 1 : 0, groupArray(ioTerm)) : <ioTermMissing> AS ioTerm,
+  arrayExists(x->x!=<amTermMissing>, groupArray(amTerm)) ? arrayFirst(x->x!=<amTermMissing> ? 1 : 0, groupArray(amTerm)) : <amTermMissing> AS amTerm,
+  arrayFirst(x->x!='<rateTypeMissing>' ? 1 : 0, groupArray(rateType)) = '' ? '<rateTypeMissing>' : arrayFirst(x->x!='<rateTypeMissing>' ? 1 : 0, groupArray(rateType)) AS rateType,
+  arrayFirst(x->x!='<noteTypeMissing>' ? 1 : 0, groupArray(noteType)) = '' ? '<noteTypeMissing>' : arrayFirst(x->x!='<noteTypeMissing>' ? 1 : 0, groupArray(noteType)) AS noteType,
+  arrayFirst(x->x!='<lienMissing>' ? 1 : 0, groupArray(lien)) = '' ? '<lienMissing>' : arrayFirst(x->x!='<lienMissing>' ? 1 : 0, groupArray(lien)) AS lien,
+  arrayFirst(x->x!='<propTypeMissing>' ? 1 : 0, groupArray(propType)) = '' ? '<propTypeMissing>' : arrayFirst(x->x!='<propTypeMissing>' ? 1 : 0, groupArray(propType)) AS propType,
+  arrayFirst(x->x!='<cityMissing>' ? 1 : 0, groupArray(city)) = '' ? '<cityMissing>' : arrayFirst(x->x!='<cityMissing>' ? 1 : 0, groupArray(city)) AS city,
+  arrayFirst(x->x!='<stateMissing>' ? 1 : 0, groupArray(state)) = '' ? '<stateMissing>' : arrayFirst(x->x!='<stateMissing>' ? 1 : 0, groupArray(state)) AS state,
+  arrayFirst(x->x!='<zipMissing>' ? 1 : 0, groupArray(zip)) = '' ? '<zipMissing>' : arrayFirst(x->x!='<zipMissing>' ? 1 : 0, groupArray(zip)) AS zip,
+  arrayFirst(x->x!='<msaMissing>' ? 1 : 0, groupArray(msa)) = '' ? '<msaMissing>' : arrayFirst(x->x!='<msaMissing>' ? 1 : 0, groupArray(msa)) AS msa,
+  arrayExists(x->x!=<yearBuiltMissing>, groupArray(yearBuilt)) ? arrayFirst(x->x!=<yearBuiltMissing> ? 1 : 0, groupArray(yearBuilt)) : <yearBuiltMissing> AS yearBuilt,
+  arrayExists(x->x!=<unitsMissing>, groupArray(units)) ? arrayFirst(x->x!=<unitsMissing> ? 1 : 0, groupArray(units)) : <unitsMissing> AS units,
+  arrayExists(x->x!=<dscrMissing>, groupArray(dscr)) ? arrayFirst(x->x!=<dscrMissing> ? 1 : 0, groupArray(dscr)) : <dscrMissing> AS dscr,
+  arrayFirst(x->x!='<dscrTypeMissing>' ? 1 : 0, groupArray(dscrType)) = '' ? '<dscrTypeMissing>' : arrayFirst(x->x!='<dscrTypeMissing>' ? 1 : 0, groupArray(dscrType)) AS dscrType,
+  arrayExists(x->x!=<ltvMissing>, groupArray(ltv)) ? arrayFirst(x->x!=<ltvMissing> ? 1 : 0, groupArray(ltv)) : <ltvMissing> AS ltv,
+  arrayFirst(x->x!='<affordTypeMissing>' ? 1 : 0, groupArray(affordType)) = '' ? '<affordTypeMissing>' : arrayFirst(x->x!='<affordTypeMissing>' ? 1 : 0, groupArray(affordType)) AS affordType,
+  arrayFirst(x->x!='<specPropTypeMissing>' ? 1 : 0, groupArray(specPropType)) = '' ? '<specPropTypeMissing>' : arrayFirst(x->x!='<specPropTypeMissing>' ? 1 : 0, groupArray(specPropType)) AS specPropType,
+  arrayFirst(x->x!='<greenMissing>' ? 1 : 0, groupArray(green)) = '' ? '<greenMissing>' : arrayFirst(x->x!='<greenMissing>' ? 1 : 0, groupArray(green)) AS green,
+
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(zbDt)) AS zbDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(pPenDt)) AS pPenDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(fclDt)) AS fclDt,
+  arrayFirst(x->year(x) > 1970 ? 1 : 0, groupArray(ceDt)) AS ceDt,
+  arrayFirst(x->x!='none' ? 1 : 0, groupArray(ceType)) = '' ? 'none' : arrayFirst(x->x!='none' ? 1 : 0, groupArray(ceType)) AS ceType,
+  arrayMax(groupArray(netLoss)) AS netLoss,
+  arrayMax(groupArray(salePrice)) AS salePrice,
+  arrayMax(groupArray(saleProc)) AS saleProc,
+  arrayElement(groupArray(file), 1) AS file,
+  arrayFirst(x->x!='<vintageMissing>' ? 1 : 0, groupArray(vintage)) = '' ? '<vintageMissing>' : arrayFirst(x->x!='<vintageMissing>' ? 1 : 0, groupArray(vintage)) AS vintage
+FROM
+  (SELECT
+    *
+  FROM
+    sourceTable as z
+  ORDER BY lnId, month
+  LIMIT 10)
+GROUP BY lnId)
+select
+  r.*,
+  toInt32(modulo(arraySum(bitPositionsToArray(reinterpretAsUInt64(substr(r.lnId, 5, 8)))), <buckets>)) AS bucket,
+  q.qa AS field,
+  q.nqa AS cntFail,
+  arrayFilter((x,y) -> y=length(month) ? 1 : 0, qa, nqa) AS allFail
+FROM
+  r
+LEFT JOIN q
+ON q.lnId = r.lnId
+`
@@ -0,0 +1,651 @@
+// Package multifamily loads the Fannie Mae Multifamily Loan Performance data.
+//
+// The multifamily data is a single comma-delimited file with a header row and one row per loan per reporting
+// period.  The field set is unrelated to the single-family files, so this package has its own TableDef and
+// follows the pattern of packages raw and collapse:
+//   - LoadRaw reads the file into a table with one row per loan per month.
+//   - GroupBy collapses that table to one row per loan with the nested table monthly.
+//
+// The package adds these fields:
+//
+//   - qa.       Results of QA.  The string field lists every field that failed QA separated by colons.
+//   - file.     Name of the source file.
+//   - vintage.  Vintage of the loan based on the acquisition date, for example 2020Q1.
+//
+// The data is available at https://datadynamics.fanniemae.com/data-dynamics/#/reportMenu;category=MF.
+package multifamily
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/chutils/file"
+	"github.com/invertedv/chutils/nested"
+	s "github.com/invertedv/chutils/sql"
+	"github.com/invertedv/fannie/raw"
+	"strings"
+	"time"
+)
+
+// TableDef is the TableDef of the table created by LoadRaw -- used by GroupBy
+var TableDef *chutils.TableDef
+
+// bufSize is the buffer size of the file readers
+const bufSize = 100000000
+
+func init() {
+	TableDef = build()
+	fds := TableDef.FieldDefs
+	next := len(fds)
+	for _, fd := range xtraFields() {
+		fds[next] = fd
+		next++
+	}
+}
+
+// IsSource returns true if fileName is a multifamily loan performance file.
+func IsSource(fileName string) bool {
+	base := raw.BaseName(fileName)
+	return strings.Contains(base, "_MF_") && strings.HasSuffix(base, ".csv")
+}
+
+// LoadRaw loads the multifamily file sourceFile into table.  sourceFile may be compressed or a member of a .zip
+// archive (see raw.Open).
+func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chutils.Connect) (err error) {
+	// fileName is a package global
+	fileName = sourceFile
+
+	f, err := raw.Open(fileName)
+	if err != nil {
+		return err
+	}
+	// the file has a header row
+	rdr := file.NewReader(fileName, ',', '\n', '"', 0, 1, 0, f, bufSize)
+	defer func() {
+		// don't throw an error if we already have one
+		if e := rdr.Close(); e != nil && err == nil {
+			err = e
+		}
+	}()
+	rdr.SetTableSpec(build())
+
+	// build slice of readers. Note: chutils.Concur will close these.
+	rdrs, err := raw.NewRdrs(rdr, nConcur, bufSize, raw.Open)
+	if err != nil {
+		return
+	}
+
+	var wrtrs []chutils.Output
+	if wrtrs, err = s.Wrtrs(table, nConcur, con); err != nil {
+		return
+	}
+
+	newCalcs := []nested.NewCalcFn{fField, vintField, vField}
+
+	rdrsn := make([]chutils.Input, 0)
+	for j, r := range rdrs {
+		rn, e := nested.NewReader(r, xtraFields(), newCalcs)
+		if e != nil {
+			return e
+		}
+		if j == 0 {
+			if e := rn.TableSpec().Check(); e != nil {
+				return e
+			}
+			if create {
+				if err = rn.TableSpec().Create(con, table); err != nil {
+					return err
+				}
+			}
+		}
+		rdrsn = append(rdrsn, rn)
+	}
+
+	err = chutils.Concur(nConcur, rdrsn, wrtrs, 100000)
+	return
+}
+
+// xtraFields defines additional fields for the nested reader
+func xtraFields() []*chutils.FieldDef {
+	ffd := &chutils.FieldDef{
+		Name:        "file",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "source file",
+		Legal:       chutils.NewLegalValues(),
+		Missing:     "!",
+	}
+	vintfd := &chutils.FieldDef{
+		Name:        "vintage",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 6, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "vintage (from acqDt)",
+		Legal:       chutils.NewLegalValues(),
+		Missing:     "XXXXQX",
+	}
+	vfd := &chutils.FieldDef{
+		Name:        "qa",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "validation results for each field: ",
+		Legal:       chutils.NewLegalValues(),
+		Missing:     "!",
+	}
+	return []*chutils.FieldDef{ffd, vintfd, vfd}
+}
+
+// fileName is global since used as a closure to fField
+var fileName string
+
+// fField returns the name of the file we're loading
+func fField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return fileName, nil
+}
+
+// vintField finds the vintage based on acqDt
+func vintField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	ind, _, err := td.Get("acqDt")
+	if err != nil {
+		return nil, err
+	}
+	acqDt := data[ind].(time.Time)
+	if acqDt.Year() == 1970 {
+		return "XXXXQX", nil
+	}
+	return fmt.Sprintf("%dQ%d", acqDt.Year(), int((acqDt.Month()-1)/3+1)), nil
+}
+
+// vField returns the names of the fields that failed validation separated by colons
+func vField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	res := make([]byte, 0)
+	res = append(res, []byte(":")...)
+	for ind, v := range valid {
+		if v != chutils.VPass && v != chutils.VDefault {
+			res = append(res, []byte(td.FieldDefs[ind].Name+":")...)
+		}
+	}
+	if len(res) > 1 {
+		return string(res), nil
+	}
+	return "", nil
+}
+
+// build builds the TableDef for the multifamily file.  The columns follow the Multifamily Loan Performance
+// Data file layout.
+func build() *chutils.TableDef {
+	var (
+		// date ranges & missing value
+		minDt  = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+		nowDt  = time.Now()
+		futDt  = time.Now().AddDate(50, 0, 0)
+		missDt = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		dtFormat = "01/02/2006"
+		strMiss  = "unknown"
+
+		lnIdMiss = "error"
+
+		acqDtMin, acqDtMax, acqDtMiss    = minDt, nowDt, missDt
+		noteDtMin, noteDtMax, noteDtMiss = minDt, nowDt, missDt
+		matDtMin, matDtMax, matDtMiss    = minDt, futDt, missDt
+
+		opbMin, opbMax, opbMiss    = float32(10000.0), float32(2000000000.0), float32(-1.0)
+		rateMin, rateMax, rateMiss = float32(0.0), float32(15.0), float32(-1.0)
+
+		termMin, termMax, termMiss                  = int32(1), int32(600), int32(-1)
+		ioTermMin, ioTermMax, ioTermMiss, ioTermDef = int32(0), int32(600), int32(-1), int32(0)
+		amTermMin, amTermMax, amTermMiss, amTermDef = int32(0), int32(600), int32(-1), int32(0)
+
+		stateMiss = "XX"
+		zipMiss   = "XXXXX"
+		msaMiss   = "XXXXX"
+
+		yearBuiltMin, yearBuiltMax, yearBuiltMiss = int32(1700), int32(nowDt.Year()), int32(-1)
+		unitsMin, unitsMax, unitsMiss             = int32(1), int32(10000), int32(-1)
+		dscrMin, dscrMax, dscrMiss                = float32(0.0), float32(20.0), float32(-1.0)
+		ltvMin, ltvMax, ltvMiss                   = float32(1.0), float32(200.0), float32(-1.0)
+
+		monthMin, monthMax, monthMiss             = minDt, nowDt, missDt
+		upbMin, upbMax, upbMiss                   = float32(0.0), float32(2000000000.0), float32(-1.0)
+		curRateMin, curRateMax, curRateMiss       = float32(0.0), float32(15.0), float32(-1.0)
+		occMin, occMax, occMiss                   = float32(0.0), float32(100.0), float32(-1.0)
+		curDscrMin, curDscrMax, curDscrMiss       = float32(-20.0), float32(20.0), float32(-1000.0)
+		zbDtMin, zbDtMax, zbDtMiss, zbDtDef       = minDt, nowDt, missDt, missDt
+		pPenDtMin, pPenDtMax, pPenDtMiss, pPenDef = minDt, futDt, missDt, missDt
+		fclDtMin, fclDtMax, fclDtMiss, fclDtDef   = minDt, nowDt, missDt, missDt
+		ceDtMin, ceDtMax, ceDtMiss, ceDtDef       = minDt, nowDt, missDt, missDt
+
+		netLossMin, netLossMax, netLossMiss, netLossDef         = float32(-2000000000.0), float32(2000000000.0), float32(-1.0), float32(0.0)
+		salePriceMin, salePriceMax, salePriceMiss, salePriceDef = float32(0.0), float32(2000000000.0), float32(-1.0), float32(0.0)
+		saleProcMin, saleProcMax, saleProcMiss, saleProcDef     = float32(0.0), float32(2000000000.0), float32(-1.0), float32(0.0)
+
+		lienMiss = "X"
+		lienLvl  = []string{"1", "2", "3", "4"}
+
+		modMiss, modDef = "X", "N"
+		modLvl          = []string{"Y", "N"}
+	)
+
+	fds := make(map[int]*chutils.FieldDef)
+
+	fd := &chutils.FieldDef{
+		Name:        "lnId",
+		ChSpec:      chutils.ChField{Base: chutils.ChString},
+		Description: "Loan ID, missing=" + lnIdMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     lnIdMiss,
+	}
+	fds[0] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "acqDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "acquisition date, missing=" + acqDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: acqDtMin, HighLimit: acqDtMax},
+		Missing:     acqDtMiss,
+	}
+	fds[1] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "noteDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "note date, missing=" + noteDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: noteDtMin, HighLimit: noteDtMax},
+		Missing:     noteDtMiss,
+	}
+	fds[2] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "matDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "maturity date at acquisition, missing=" + matDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: matDtMin, HighLimit: matDtMax},
+		Missing:     matDtMiss,
+	}
+	fds[3] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "opb",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "balance at acquisition, missing=" + fmt.Sprintf("%v", opbMiss),
+		Legal:       &chutils.LegalValues{LowLimit: opbMin, HighLimit: opbMax},
+		Missing:     opbMiss,
+	}
+	fds[4] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "amType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "amortization type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[5] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "rate",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "note rate at acquisition, 0-15, missing=" + fmt.Sprintf("%v", rateMiss),
+		Legal:       &chutils.LegalValues{LowLimit: rateMin, HighLimit: rateMax},
+		Missing:     rateMiss,
+	}
+	fds[6] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "product",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "loan product type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[7] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "term",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "loan term at origination, missing=" + fmt.Sprintf("%v", termMiss),
+		Legal:       &chutils.LegalValues{LowLimit: termMin, HighLimit: termMax},
+		Missing:     termMiss,
+	}
+	fds[8] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ioTerm",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "interest-only term at origination, missing=" + fmt.Sprintf("%v", ioTermMiss),
+		Legal:       &chutils.LegalValues{LowLimit: ioTermMin, HighLimit: ioTermMax},
+		Missing:     ioTermMiss,
+		Default:     ioTermDef,
+	}
+	fds[9] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "amTerm",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "amortization term, missing=" + fmt.Sprintf("%v", amTermMiss),
+		Legal:       &chutils.LegalValues{LowLimit: amTermMin, HighLimit: amTermMax},
+		Missing:     amTermMiss,
+		Default:     amTermDef,
+	}
+	fds[10] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "rateType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "interest type (fixed, variable), missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[11] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "noteType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "note type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[12] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "lien",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "lien position: 1, 2, 3, 4, missing=" + lienMiss,
+		Legal:       &chutils.LegalValues{Levels: lienLvl},
+		Missing:     lienMiss,
+	}
+	fds[13] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "propType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "property type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[14] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "city",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "property city, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[15] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "state",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 2},
+		Description: "property state, missing=" + stateMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     stateMiss,
+		Default:     stateMiss,
+	}
+	fds[16] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "zip",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 5},
+		Description: "property zip code, missing=" + zipMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     zipMiss,
+		Default:     zipMiss,
+	}
+	fds[17] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "msa",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 5},
+		Description: "msa/division code, missing=" + msaMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     msaMiss,
+		Default:     msaMiss,
+	}
+	fds[18] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "yearBuilt",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "year property was built, missing=" + fmt.Sprintf("%v", yearBuiltMiss),
+		Legal:       &chutils.LegalValues{LowLimit: yearBuiltMin, HighLimit: yearBuiltMax},
+		Missing:     yearBuiltMiss,
+	}
+	fds[19] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "units",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "number of units, missing=" + fmt.Sprintf("%v", unitsMiss),
+		Legal:       &chutils.LegalValues{LowLimit: unitsMin, HighLimit: unitsMax},
+		Missing:     unitsMiss,
+	}
+	fds[20] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "dscr",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "underwritten DSCR, missing=" + fmt.Sprintf("%v", dscrMiss),
+		Legal:       &chutils.LegalValues{LowLimit: dscrMin, HighLimit: dscrMax},
+		Missing:     dscrMiss,
+	}
+	fds[21] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "dscrType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "underwritten DSCR type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[22] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ltv",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "ltv at acquisition, missing=" + fmt.Sprintf("%v", ltvMiss),
+		Legal:       &chutils.LegalValues{LowLimit: ltvMin, HighLimit: ltvMax},
+		Missing:     ltvMiss,
+	}
+	fds[23] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "affordType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "affordable housing type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[24] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "specPropType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "specific property type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[25] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "green",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "green financing type, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[26] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "month",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "reporting period, missing=" + monthMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: monthMin, HighLimit: monthMax},
+		Missing:     monthMiss,
+	}
+	fds[27] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "payStat",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "loan payment status, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[28] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "upb",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "unpaid balance, missing=" + fmt.Sprintf("%v", upbMiss),
+		Legal:       &chutils.LegalValues{LowLimit: upbMin, HighLimit: upbMax},
+		Missing:     upbMiss,
+	}
+	fds[29] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "curRate",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "current note rate, missing=" + fmt.Sprintf("%v", curRateMiss),
+		Legal:       &chutils.LegalValues{LowLimit: curRateMin, HighLimit: curRateMax},
+		Missing:     curRateMiss,
+	}
+	fds[30] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "occ",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "most recent physical occupancy (%), missing=" + fmt.Sprintf("%v", occMiss),
+		Legal:       &chutils.LegalValues{LowLimit: occMin, HighLimit: occMax},
+		Missing:     occMiss,
+	}
+	fds[31] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "curDscr",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "most recent DSCR, missing=" + fmt.Sprintf("%v", curDscrMiss),
+		Legal:       &chutils.LegalValues{LowLimit: curDscrMin, HighLimit: curDscrMax},
+		Missing:     curDscrMiss,
+	}
+	fds[32] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "zb",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "liquidation/prepayment code, none=active",
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     "none",
+	}
+	fds[33] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "zbDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "liquidation/prepayment date, missing=" + zbDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: zbDtMin, HighLimit: zbDtMax},
+		Missing:     zbDtMiss,
+		Default:     zbDtDef,
+	}
+	fds[34] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "pPenProv",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "prepayment provision, missing=" + strMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     strMiss,
+	}
+	fds[35] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "pPenDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "prepayment provision end date, missing=" + pPenDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: pPenDtMin, HighLimit: pPenDtMax},
+		Missing:     pPenDtMiss,
+		Default:     pPenDef,
+	}
+	fds[36] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "fclDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "foreclosure date, missing=" + fclDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: fclDtMin, HighLimit: fclDtMax},
+		Missing:     fclDtMiss,
+		Default:     fclDtDef,
+	}
+	fds[37] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ceDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: dtFormat},
+		Description: "credit event date, missing=" + ceDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: ceDtMin, HighLimit: ceDtMax},
+		Missing:     ceDtMiss,
+		Default:     ceDtDef,
+	}
+	fds[38] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ceType",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "credit event type, none=no credit event",
+		Legal:       &chutils.LegalValues{},
+		Missing:     strMiss,
+		Default:     "none",
+	}
+	fds[39] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "netLoss",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "lifetime net credit loss, missing=" + fmt.Sprintf("%v", netLossMiss),
+		Legal:       &chutils.LegalValues{LowLimit: netLossMin, HighLimit: netLossMax},
+		Missing:     netLossMiss,
+		Default:     netLossDef,
+	}
+	fds[40] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "salePrice",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "sales price, missing=" + fmt.Sprintf("%v", salePriceMiss),
+		Legal:       &chutils.LegalValues{LowLimit: salePriceMin, HighLimit: salePriceMax},
+		Missing:     salePriceMiss,
+		Default:     salePriceDef,
+	}
+	fds[41] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "saleProc",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "gross sale proceeds, missing=" + fmt.Sprintf("%v", saleProcMiss),
+		Legal:       &chutils.LegalValues{LowLimit: saleProcMin, HighLimit: saleProcMax},
+		Missing:     saleProcMiss,
+		Default:     saleProcDef,
+	}
+	fds[42] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "mod",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "loan modified: Y, N, missing=" + modMiss,
+		Legal:       &chutils.LegalValues{Levels: modLvl},
+		Missing:     modMiss,
+		Default:     modDef,
+	}
+	fds[43] = fd
+
+	return chutils.NewTableDef("lnId, month", chutils.MergeTree, fds)
+}
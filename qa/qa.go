@@ -0,0 +1,104 @@
+// Package qa maintains a ClickHouse table that summarizes, for each source file, the validation results of each field.
+//
+// The table has one row per file per field with the # of rows checked, failed and set to the default value along
+// with a sample of the values that failed.  A file's rows are replaced when it is reloaded.
+//
+// The summary can be rendered as JSON or as a standalone HTML page (see WriteJSON, WriteHTML).
+package qa
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/fannie/raw"
+	"time"
+)
+
+// Summary is a row of the QA summary table
+type Summary struct {
+	File      string    `json:"file"`      // File is the source file
+	Field     string    `json:"field"`     // Field is the name of the field
+	Rows      int64     `json:"rows"`      // Rows is the # of rows checked
+	Failed    int64     `json:"failed"`    // Failed is the # of rows that failed validation
+	Defaulted int64     `json:"defaulted"` // Defaulted is the # of rows set to the default value
+	Samples   []string  `json:"samples"`   // Samples are up to 5 distinct values that failed validation
+	Loaded    time.Time `json:"loaded"`    // Loaded is the time the summary was written
+}
+
+// PctFailed returns the percentage of rows that failed validation
+func (s *Summary) PctFailed() float64 {
+	if s.Rows == 0 {
+		return 0.0
+	}
+	return 100.0 * float64(s.Failed) / float64(s.Rows)
+}
+
+// Create creates the QA summary table if it does not exist.
+func Create(table string, con *chutils.Connect) error {
+	_, err := con.Exec(fmt.Sprintf(createQry, table))
+	return err
+}
+
+// Write replaces the rows for sourceFile in table with fss, the summary of the load of sourceFile (see raw.Summaries).
+func Write(table string, sourceFile string, fss []*raw.FieldSummary, con *chutils.Connect) error {
+	qry := fmt.Sprintf("ALTER TABLE %s DELETE WHERE file = ? SETTINGS mutations_sync = 1", table)
+	if _, err := con.Exec(qry, sourceFile); err != nil {
+		return err
+	}
+	tx, err := con.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?, ?, ?, ?, ?)", table))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	now := time.Now()
+	for _, fs := range fss {
+		if _, e := stmt.Exec(sourceFile, fs.Field, fs.Rows, fs.Failed, fs.Defaulted, fs.Samples, now); e != nil {
+			_ = tx.Rollback()
+			return e
+		}
+	}
+	return tx.Commit()
+}
+
+// Read reads the summary from table.  If sourceFile is not empty, only that file is returned.
+func Read(table string, sourceFile string, con *chutils.Connect) ([]*Summary, error) {
+	qry := fmt.Sprintf("SELECT file, field, rows, failed, defaulted, samples, loaded FROM %s", table)
+	args := make([]interface{}, 0)
+	if sourceFile != "" {
+		qry += " WHERE file = ?"
+		args = append(args, sourceFile)
+	}
+	qry += " ORDER BY file, field"
+	rows, err := con.Query(qry, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	sums := make([]*Summary, 0)
+	for rows.Next() {
+		s := &Summary{}
+		if e := rows.Scan(&s.File, &s.Field, &s.Rows, &s.Failed, &s.Defaulted, &s.Samples, &s.Loaded); e != nil {
+			return nil, e
+		}
+		sums = append(sums, s)
+	}
+	return sums, rows.Err()
+}
+
+// createQry creates the QA summary table.  There is a placeholder for the table name.
+const createQry = `
+CREATE TABLE IF NOT EXISTS %s (
+  file      String        COMMENT 'source file',
+  field     String        COMMENT 'field name',
+  rows      Int64         COMMENT '# of rows checked',
+  failed    Int64         COMMENT '# of rows that failed validation',
+  defaulted Int64         COMMENT '# of rows set to the default value',
+  samples   Array(String) COMMENT 'sample of values that failed validation',
+  loaded    DateTime      COMMENT 'time the summary was written'
+) ENGINE=MergeTree()
+ORDER BY (file, field)
+`
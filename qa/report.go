@@ -0,0 +1,61 @@
+package qa
+
+import (
+	"encoding/json"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteJSON writes the summary as a JSON array.
+func WriteJSON(w io.Writer, sums []*Summary) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sums)
+}
+
+// WriteHTML writes the summary as a standalone HTML page with a table per file.
+func WriteHTML(w io.Writer, sums []*Summary) error {
+	files := make([]*fileReport, 0)
+	for _, s := range sums {
+		if len(files) == 0 || files[len(files)-1].File != s.File {
+			files = append(files, &fileReport{File: s.File})
+		}
+		files[len(files)-1].Fields = append(files[len(files)-1].Fields, s)
+	}
+	return page.Execute(w, files)
+}
+
+// fileReport holds the summary of one file for the HTML page
+type fileReport struct {
+	File   string
+	Fields []*Summary
+}
+
+// page is the template of the HTML report
+var page = template.Must(template.New("qa").Funcs(template.FuncMap{"join": strings.Join}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>QA Summary</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: right; }
+th { background: #eee; }
+td.name { text-align: left; }
+tr.fail td { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>QA Summary</h1>
+{{range .}}
+<h2>{{.File}}</h2>
+<table>
+<tr><th>field</th><th>rows</th><th>failed</th><th>% failed</th><th>defaulted</th><th>sample bad values</th></tr>
+{{range .Fields}}<tr{{if gt .Failed 0}} class="fail"{{end}}><td class="name">{{.Field}}</td><td>{{.Rows}}</td><td>{{.Failed}}</td><td>{{printf "%0.2f" .PctFailed}}</td><td>{{.Defaulted}}</td><td class="name">{{join .Samples ", "}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
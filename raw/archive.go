@@ -0,0 +1,247 @@
+package raw
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/chutils/file"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"strings"
+)
+
+// Members returns the names of the loadable sources in the file path.  For a .zip archive, there is one entry
+// per member.  For all other files, the only entry is path itself.
+//
+// Sources are named as follows:
+//   - .zip.  The path to the archive followed by the member, e.g. /data/2020Q1.zip/2020Q1.csv.
+//   - all others (including .gz, .zst).  The path to the file, e.g. /data/2020Q1.csv.gz.
+//
+// The source name is what is recorded in the file field.
+func Members(path string) ([]string, error) {
+	if !strings.HasSuffix(strings.ToLower(path), ".zip") {
+		return []string{path}, nil
+	}
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = zr.Close() }()
+
+	members := make([]string, 0)
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		members = append(members, path+"/"+f.Name)
+	}
+	return members, nil
+}
+
+// BaseName returns the name of the source with any compression suffix (.gz, .zst) removed.  For .zip members,
+// this is the name of the member.
+func BaseName(source string) string {
+	name := source
+	if ind := strings.LastIndex(name, "/"); ind >= 0 {
+		name = name[ind+1:]
+	}
+	for _, sfx := range []string{".gz", ".zst"} {
+		if strings.HasSuffix(strings.ToLower(name), sfx) {
+			return name[:len(name)-len(sfx)]
+		}
+	}
+	return name
+}
+
+// splitMember splits the source name of a .zip member into the archive and the member.  ok is false if source is
+// not a .zip member.
+func splitMember(source string) (archive string, member string, ok bool) {
+	ind := strings.Index(strings.ToLower(source), ".zip/")
+	if ind < 0 {
+		return "", "", false
+	}
+	return source[:ind+len(".zip")], source[ind+len(".zip/"):], true
+}
+
+// IsMember returns true if source is a member of a .zip archive.
+func IsMember(source string) bool {
+	_, _, ok := splitMember(source)
+	return ok
+}
+
+// MemberCRC returns the CRC-32 and uncompressed size of the .zip member source as recorded in the archive, so the
+// member is not read.
+func MemberCRC(source string) (crc uint32, size int64, err error) {
+	archive, member, ok := splitMember(source)
+	if !ok {
+		return 0, 0, fmt.Errorf("%s is not a member of a .zip archive", source)
+	}
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer func() { _ = zr.Close() }()
+	for _, f := range zr.File {
+		if f.Name == member {
+			return f.CRC32, int64(f.UncompressedSize64), nil
+		}
+	}
+	return 0, 0, fmt.Errorf("member %s not found in %s", member, archive)
+}
+
+// Open opens the source for reading.  Compressed sources are decompressed as they are read.
+// Legacy Performance files are presented in the sf2020 layout (see openLegacy).
+func Open(source string) (io.ReadSeekCloser, error) {
+	if isLegacy(source) {
+		return openLegacy(source)
+	}
+	return openPlain(source)
+}
+
+// openPlain opens the source for reading with no conversion of the layout.
+func openPlain(source string) (io.ReadSeekCloser, error) {
+	lower := strings.ToLower(source)
+	if archive, member, ok := splitMember(source); ok {
+		return newStream(func() (io.ReadCloser, error) { return openZip(archive, member) })
+	}
+	switch {
+	case strings.HasSuffix(lower, ".gz"):
+		return newStream(func() (io.ReadCloser, error) { return openGzip(source) })
+	case strings.HasSuffix(lower, ".zst"):
+		return newStream(func() (io.ReadCloser, error) { return openZstd(source) })
+	}
+	return os.Open(source)
+}
+
+// stream is an io.ReadSeekCloser over a decompressed source.  The only seek supported is to the start of the
+// source, which is done by re-opening the source.  This is all file.Reader requires.
+type stream struct {
+	open func() (io.ReadCloser, error)
+	rc   io.ReadCloser
+}
+
+func newStream(open func() (io.ReadCloser, error)) (*stream, error) {
+	rc, err := open()
+	if err != nil {
+		return nil, err
+	}
+	return &stream{open: open, rc: rc}, nil
+}
+
+func (s *stream) Read(p []byte) (int, error) {
+	return s.rc.Read(p)
+}
+
+func (s *stream) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, fmt.Errorf("compressed sources can only seek to the start")
+	}
+	if e := s.rc.Close(); e != nil {
+		return 0, e
+	}
+	rc, err := s.open()
+	if err != nil {
+		return 0, err
+	}
+	s.rc = rc
+	return 0, nil
+}
+
+func (s *stream) Close() error {
+	return s.rc.Close()
+}
+
+// readCloser closes the decompressor and then the underlying file
+type readCloser struct {
+	io.Reader
+	closers []func() error
+}
+
+func (r *readCloser) Close() (err error) {
+	for _, c := range r.closers {
+		if e := c(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+func openGzip(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: gz, closers: []func() error{gz.Close, f.Close}}, nil
+}
+
+func openZstd(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zstd.NewReader(f)
+	if err != nil {
+		_ = f.Close()
+		return nil, err
+	}
+	return &readCloser{Reader: zr, closers: []func() error{func() error { zr.Close(); return nil }, f.Close}}, nil
+}
+
+func openZip(archive string, member string) (io.ReadCloser, error) {
+	zr, err := zip.OpenReader(archive)
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range zr.File {
+		if f.Name != member {
+			continue
+		}
+		rc, e := f.Open()
+		if e != nil {
+			_ = zr.Close()
+			return nil, e
+		}
+		return &readCloser{Reader: rc, closers: []func() error{rc.Close, zr.Close}}, nil
+	}
+	_ = zr.Close()
+	return nil, fmt.Errorf("member %s not found in %s", member, archive)
+}
+
+// NewRdrs generates a slice of nRdrs readers which divide the data of rdr0 equally.  This is file.Rdrs except the
+// sources are opened with open, so compressed and joined sources are supported.
+func NewRdrs(rdr0 *file.Reader, nRdrs int, bufSize int, open Opener) (r []chutils.Input, err error) {
+	if nRdrs < 1 {
+		return nil, chutils.Wrapper(chutils.ErrInput, "must have >= 1 reader")
+	}
+	nObs, err := rdr0.CountLines()
+	if err != nil {
+		return nil, err
+	}
+	nper := nObs / nRdrs
+	start := 1
+	for ind := 0; ind < nRdrs; ind++ {
+		rws, e := open(rdr0.Name())
+		if e != nil {
+			return nil, e
+		}
+		np := start + nper - 1
+		if ind == nRdrs-1 {
+			np = 0
+		}
+		x := file.NewReader(rdr0.Name(), rdr0.Separator(), rdr0.EOL(), rdr0.Quote, rdr0.Width, rdr0.Skip, np, rws, bufSize)
+		x.SetTableSpec(rdr0.TableSpec())
+		if e := x.Seek(start); e != nil {
+			return nil, e
+		}
+		start += nper
+		r = append(r, x)
+	}
+	return r, nil
+}
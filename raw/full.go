@@ -0,0 +1,408 @@
+package raw
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"time"
+)
+
+// Full is true if the columns of the sf2020 layout that the lean schema drops are loaded.  Set it with SetFull.
+var Full bool
+
+// SetFull sets Full and rebuilds TableDef.
+func SetFull(full bool) {
+	Full = full
+	TableDef = tableDef()
+}
+
+// full defines the FieldDefs of the columns that are loaded only if Full is true, keyed by their position in the
+// sf2020 layout.  The columns that hold the crt() fields are not included -- build loads the cumulative losses
+// (modLossCum, ceLossCum) from these if Full.
+func full() map[int]*chutils.FieldDef {
+	var (
+		minDt  = time.Date(1999, 1, 1, 0, 0, 0, 0, time.UTC)
+		nowDt  = time.Now()
+		futDt  = time.Now().AddDate(40, 0, 0)
+		missDt = time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
+
+		strMiss = "X" // generic missing value for FixedString(1)
+		ynLvl   = []string{"Y", "N", "7", "9"}
+
+		mServicerMiss = "unknown"
+
+		upbIssueMin, upbIssueMax, upbIssueMiss = float32(0.0), float32(2000000.0), float32(-1.0)
+
+		miCancelMiss, miCancelDef = strMiss, "7"
+
+		reprchDtMin, reprchDtMax, reprchDtMiss = minDt, nowDt, missDt
+
+		schedPrinRptMin, schedPrinRptMax, schedPrinRptMiss, schedPrinRptDef         = float32(0.0), float32(2000000.0), float32(-1.0), float32(0.0)
+		unschedPrinRptMin, unschedPrinRptMax, unschedPrinRptMiss, unschedPrinRptDef = float32(0.0), float32(2000000.0), float32(-1.0), float32(0.0)
+
+		listDtMin, listDtMax, listDtMiss                = minDt, nowDt, missDt
+		listPriceMin, listPriceMax, listPriceMiss       = float32(1000.0), float32(10000000.0), float32(-1.0)
+		ficoMin, ficoMax, ficoMiss                      = int32(301), int32(850), int32(-1)
+		modLossMin, modLossMax, modLossMiss, modLossDef = float32(-2000000.0), float32(2000000.0), float32(-1.0), float32(0.0)
+		ceLossMin, ceLossMax, ceLossMiss, ceLossDef     = float32(-2000000.0), float32(2000000.0), float32(-1.0), float32(0.0)
+
+		zbChgDtMin, zbChgDtMax, zbChgDtMiss = minDt, nowDt, missDt
+
+		holdbackMiss, holdbackDef                    = strMiss, "7"
+		holdbackDtMin, holdbackDtMax, holdbackDtMiss = minDt, nowDt, missDt
+
+		dqIntRptMin, dqIntRptMax, dqIntRptMiss = float32(0.0), float32(200000.0), float32(-1.0)
+
+		armLe5Miss, armLe5Def = strMiss, "7"
+		armMiss               = "unknown"
+
+		armInitPerMin, armInitPerMax, armInitPerMiss = int32(0), int32(600), int32(-1)
+		armAdjFreqMin, armAdjFreqMax, armAdjFreqMiss = int32(0), int32(120), int32(-1)
+
+		armAdjDtMin, armAdjDtMax, armAdjDtMiss = minDt, futDt, missDt
+
+		armCapMin, armCapMax, armCapMiss          = float32(0.0), float32(15.0), float32(-1.0)
+		armMarginMin, armMarginMax, armMarginMiss = float32(0.0), float32(15.0), float32(-1.0)
+
+		balloonMiss, balloonDef = strMiss, "N"
+	)
+
+	fds := make(map[int]*chutils.FieldDef)
+
+	fd := &chutils.FieldDef{
+		Name:        "mServicer",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "master servicer, missing=" + mServicerMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     mServicerMiss,
+		Default:     mServicerMiss,
+	}
+	fds[6] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "upbIssue",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "unpaid balance at issuance, missing=" + fmt.Sprintf("%v", upbIssueMiss),
+		Legal:       &chutils.LegalValues{LowLimit: upbIssueMin, HighLimit: upbIssueMax},
+		Missing:     upbIssueMiss,
+		Default:     upbIssueMiss,
+	}
+	fds[10] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "miCancel",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "mortgage insurance cancellation indicator: Y, N, 7,9(NA), missing=" + miCancelMiss,
+		Legal:       &chutils.LegalValues{Levels: ynLvl},
+		Missing:     miCancelMiss,
+		Default:     miCancelDef,
+	}
+	fds[42] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "reprchDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "repurchase date, missing=" + reprchDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: reprchDtMin, HighLimit: reprchDtMax},
+		Missing:     reprchDtMiss,
+		Default:     reprchDtMiss,
+	}
+	fds[46] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "schedPrinRpt",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "scheduled principal as reported, missing=" + fmt.Sprintf("%v", schedPrinRptMiss),
+		Legal:       &chutils.LegalValues{LowLimit: schedPrinRptMin, HighLimit: schedPrinRptMax},
+		Missing:     schedPrinRptMiss,
+		Default:     schedPrinRptDef,
+	}
+	fds[47] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "unschedPrinRpt",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "unscheduled principal as reported, missing=" + fmt.Sprintf("%v", unschedPrinRptMiss),
+		Legal:       &chutils.LegalValues{LowLimit: unschedPrinRptMin, HighLimit: unschedPrinRptMax},
+		Missing:     unschedPrinRptMiss,
+		Default:     unschedPrinRptDef,
+	}
+	fds[49] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "origListDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "original list start date, missing=" + listDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: listDtMin, HighLimit: listDtMax},
+		Missing:     listDtMiss,
+		Default:     listDtMiss,
+	}
+	fds[64] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "origListPrice",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "original list price, missing=" + fmt.Sprintf("%v", listPriceMiss),
+		Legal:       &chutils.LegalValues{LowLimit: listPriceMin, HighLimit: listPriceMax},
+		Missing:     listPriceMiss,
+		Default:     listPriceMiss,
+	}
+	fds[65] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "curListDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "current list start date, missing=" + listDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: listDtMin, HighLimit: listDtMax},
+		Missing:     listDtMiss,
+		Default:     listDtMiss,
+	}
+	fds[66] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "curListPrice",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "current list price, missing=" + fmt.Sprintf("%v", listPriceMiss),
+		Legal:       &chutils.LegalValues{LowLimit: listPriceMin, HighLimit: listPriceMax},
+		Missing:     listPriceMiss,
+		Default:     listPriceMiss,
+	}
+	fds[67] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ficoIssue",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "borrower fico at issuance, 301-850, missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: ficoMin, HighLimit: ficoMax},
+		Missing:     ficoMiss,
+	}
+	fds[68] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "coFicoIssue",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "co-borrower fico at issuance, 301-850, missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: ficoMin, HighLimit: ficoMax},
+		Missing:     ficoMiss,
+	}
+	fds[69] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ficoCur",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "current borrower fico, 301-850, missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: ficoMin, HighLimit: ficoMax},
+		Missing:     ficoMiss,
+	}
+	fds[70] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "coFicoCur",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "current co-borrower fico, 301-850, missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: ficoMin, HighLimit: ficoMax},
+		Missing:     ficoMiss,
+	}
+	fds[71] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "modLoss",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "current period modification loss, missing=" + fmt.Sprintf("%v", modLossMiss),
+		Legal:       &chutils.LegalValues{LowLimit: modLossMin, HighLimit: modLossMax},
+		Missing:     modLossMiss,
+		Default:     modLossDef,
+	}
+	fds[74] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "ceLoss",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "current period credit event net gain (+)/loss (-), missing=" + fmt.Sprintf("%v", ceLossMiss),
+		Legal:       &chutils.LegalValues{LowLimit: ceLossMin, HighLimit: ceLossMax},
+		Missing:     ceLossMiss,
+		Default:     ceLossDef,
+	}
+	fds[76] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "zbChgDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "zero balance code change date, missing=" + zbChgDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: zbChgDtMin, HighLimit: zbChgDtMax},
+		Missing:     zbChgDtMiss,
+		Default:     zbChgDtMiss,
+	}
+	fds[81] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "holdback",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "loan holdback indicator: Y, N, 7,9(NA), missing=" + holdbackMiss,
+		Legal:       &chutils.LegalValues{Levels: ynLvl},
+		Missing:     holdbackMiss,
+		Default:     holdbackDef,
+	}
+	fds[82] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "holdbackDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "loan holdback effective date, missing=" + holdbackDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: holdbackDtMin, HighLimit: holdbackDtMax},
+		Missing:     holdbackDtMiss,
+		Default:     holdbackDtMiss,
+	}
+	fds[83] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "dqIntRpt",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "delinquent accrued interest as reported, missing=" + fmt.Sprintf("%v", dqIntRptMiss),
+		Legal:       &chutils.LegalValues{LowLimit: dqIntRptMin, HighLimit: dqIntRptMax},
+		Missing:     dqIntRptMiss,
+		Default:     dqIntRptMiss,
+	}
+	fds[84] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armLe5",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "ARM initial fixed-rate period <= 5 years: Y, N, 7,9(NA), missing=" + armLe5Miss,
+		Legal:       &chutils.LegalValues{Levels: ynLvl},
+		Missing:     armLe5Miss,
+		Default:     armLe5Def,
+	}
+	fds[87] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armProduct",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "ARM product type, missing=" + armMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     armMiss,
+		Default:     armMiss,
+	}
+	fds[88] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armInitPer",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "ARM initial fixed-rate period in months, missing=" + fmt.Sprintf("%v", armInitPerMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armInitPerMin, HighLimit: armInitPerMax},
+		Missing:     armInitPerMiss,
+		Default:     armInitPerMiss,
+	}
+	fds[89] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armAdjFreq",
+		ChSpec:      chutils.ChField{Base: chutils.ChInt, Length: 32},
+		Description: "ARM interest rate adjustment frequency in months, missing=" + fmt.Sprintf("%v", armAdjFreqMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armAdjFreqMin, HighLimit: armAdjFreqMax},
+		Missing:     armAdjFreqMiss,
+		Default:     armAdjFreqMiss,
+	}
+	fds[90] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armNextAdjDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "ARM next interest rate adjustment date, missing=" + armAdjDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: armAdjDtMin, HighLimit: armAdjDtMax},
+		Missing:     armAdjDtMiss,
+		Default:     armAdjDtMiss,
+	}
+	fds[91] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armNextPayDt",
+		ChSpec:      chutils.ChField{Base: chutils.ChDate, Format: "012006"},
+		Description: "ARM next payment change date, missing=" + armAdjDtMiss.Format("2006/1/2"),
+		Legal:       &chutils.LegalValues{LowLimit: armAdjDtMin, HighLimit: armAdjDtMax},
+		Missing:     armAdjDtMiss,
+		Default:     armAdjDtMiss,
+	}
+	fds[92] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armIndex",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "ARM index, missing=" + armMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     armMiss,
+		Default:     armMiss,
+	}
+	fds[93] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armCapStruct",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "ARM cap structure, missing=" + armMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     armMiss,
+		Default:     armMiss,
+	}
+	fds[94] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armInitCap",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "ARM initial interest rate cap, missing=" + fmt.Sprintf("%v", armCapMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armCapMin, HighLimit: armCapMax},
+		Missing:     armCapMiss,
+		Default:     armCapMiss,
+	}
+	fds[95] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armPerCap",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "ARM periodic interest rate cap, missing=" + fmt.Sprintf("%v", armCapMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armCapMin, HighLimit: armCapMax},
+		Missing:     armCapMiss,
+		Default:     armCapMiss,
+	}
+	fds[96] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armLifeCap",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "ARM lifetime interest rate cap, missing=" + fmt.Sprintf("%v", armCapMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armCapMin, HighLimit: armCapMax},
+		Missing:     armCapMiss,
+		Default:     armCapMiss,
+	}
+	fds[97] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "armMargin",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "ARM mortgage margin, missing=" + fmt.Sprintf("%v", armMarginMiss),
+		Legal:       &chutils.LegalValues{LowLimit: armMarginMin, HighLimit: armMarginMax},
+		Missing:     armMarginMiss,
+		Default:     armMarginMiss,
+	}
+	fds[98] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "balloon",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 1},
+		Description: "balloon indicator: Y, N, 7,9(NA), missing=" + balloonMiss,
+		Legal:       &chutils.LegalValues{Levels: ynLvl},
+		Missing:     balloonMiss,
+		Default:     balloonDef,
+	}
+	fds[99] = fd
+
+	fd = &chutils.FieldDef{
+		Name:        "planNum",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "ARM plan number, missing=" + armMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     armMiss,
+		Default:     armMiss,
+	}
+	fds[100] = fd
+
+	return fds
+}
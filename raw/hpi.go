@@ -0,0 +1,197 @@
+package raw
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// HPI is a house price index by MSA/CBSA and by state.
+type HPI struct {
+	msa   map[string]map[int]float64 // msa is the index by CBSA code and month
+	state map[string]map[int]float64 // state is the index by state and month
+}
+
+// Hpi is the house price index used to calculate hpiVal and eLtv.  If nil, these are missing.
+var Hpi *HPI
+
+// LoadHPI loads the house price index in sourceFile into Hpi.
+//
+// The file is an FHFA-style comma delimited file with a header.  The columns used are level (MSA or State),
+// place_id (CBSA code or state abbreviation), frequency (monthly or quarterly), yr, period (month or quarter) and
+// index_nsa (index_sa if index_nsa is empty).  Quarterly values apply to each month of the quarter.  A place with
+// monthly values does not use its quarterly values.  If a place has several values for a month (e.g. several
+// index flavors), the first is used.
+func LoadHPI(sourceFile string) error {
+	f, err := Open(sourceFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	h, err := readHPI(f)
+	if err != nil {
+		return fmt.Errorf("hpi %s: %v", sourceFile, err)
+	}
+	Hpi = h
+	return nil
+}
+
+// readHPI reads an HPI file
+func readHPI(r io.Reader) (*HPI, error) {
+	rdr := csv.NewReader(r)
+	rdr.FieldsPerRecord = -1
+	header, err := rdr.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string]int)
+	for ind, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = ind
+	}
+	for _, name := range []string{"level", "place_id", "frequency", "yr", "period", "index_nsa"} {
+		if _, ok := cols[name]; !ok {
+			return nil, fmt.Errorf("missing column %s", name)
+		}
+	}
+	sa, hasSa := cols["index_sa"]
+
+	// monthly and quarterly values are kept apart, so monthly values take precedence
+	monthly, quarterly := make(map[string]map[int]float64), make(map[string]map[int]float64)
+	for lineNo := 2; ; lineNo++ {
+		row, e := rdr.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		level := strings.ToLower(strings.TrimSpace(row[cols["level"]]))
+		if level != "msa" && level != "state" {
+			continue
+		}
+		val := strings.TrimSpace(row[cols["index_nsa"]])
+		if val == "" && hasSa {
+			val = strings.TrimSpace(row[sa])
+		}
+		if val == "" {
+			continue
+		}
+		idx, e1 := strconv.ParseFloat(val, 64)
+		yr, e2 := strconv.Atoi(strings.TrimSpace(row[cols["yr"]]))
+		period, e3 := strconv.Atoi(strings.TrimSpace(row[cols["period"]]))
+		if e1 != nil || e2 != nil || e3 != nil {
+			return nil, fmt.Errorf("line %d: bad yr, period or index", lineNo)
+		}
+		key := level + ":" + strings.TrimSpace(row[cols["place_id"]])
+
+		var months []int
+		target := monthly
+		switch strings.ToLower(strings.TrimSpace(row[cols["frequency"]])) {
+		case "monthly":
+			months = []int{12*yr + period - 1}
+		case "quarterly":
+			months = []int{12*yr + 3*period - 3, 12*yr + 3*period - 2, 12*yr + 3*period - 1}
+			target = quarterly
+		default:
+			continue
+		}
+		if target[key] == nil {
+			target[key] = make(map[int]float64)
+		}
+		for _, m := range months {
+			if _, ok := target[key][m]; !ok {
+				target[key][m] = idx
+			}
+		}
+	}
+
+	h := &HPI{msa: make(map[string]map[int]float64), state: make(map[string]map[int]float64)}
+	for key, idx := range quarterly {
+		if _, ok := monthly[key]; !ok {
+			monthly[key] = idx
+		}
+	}
+	for key, idx := range monthly {
+		level, place, _ := strings.Cut(key, ":")
+		if level == "msa" {
+			h.msa[place] = idx
+			continue
+		}
+		h.state[place] = idx
+	}
+	return h, nil
+}
+
+// monthKey is the key of the month of dt in the index maps
+func monthKey(dt time.Time) int {
+	return 12*dt.Year() + int(dt.Month()) - 1
+}
+
+// ratio returns the ratio of the index at month to the index at base for msa, falling back to state
+func (h *HPI) ratio(msa string, state string, base time.Time, month time.Time) (float64, bool) {
+	for _, idx := range []map[int]float64{h.msa[msa], h.state[state]} {
+		b, ok1 := idx[monthKey(base)]
+		m, ok2 := idx[monthKey(month)]
+		if ok1 && ok2 && b > 0 {
+			return m / b, true
+		}
+	}
+	return 0, false
+}
+
+// hpiValue returns propVal indexed by Hpi from fpDt to month
+func hpiValue(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) (float32, bool) {
+	if Hpi == nil {
+		return 0, false
+	}
+	pv, err := pvField(td, data, valid, true)
+	if err != nil {
+		return 0, false
+	}
+	propVal, ok := pv.(float32)
+	if !ok || propVal <= 0 {
+		return 0, false
+	}
+	fpDt, ok1 := value(td, data, valid, "fpDt")
+	month, ok2 := value(td, data, valid, "month")
+	state, ok3 := value(td, data, valid, "state")
+	if !ok1 || !ok2 || !ok3 {
+		return 0, false
+	}
+	// the msa may not have passed validation if it is deprecated
+	msa := ""
+	if ind, _, e := td.Get("msa"); e == nil {
+		msa = data[ind].(string)
+		if newMsa, ok := MsaCrosswalk.Map[msa]; ok {
+			msa = newMsa
+		}
+	}
+	r, ok := Hpi.ratio(msa, state.(string), fpDt.(time.Time), month.(time.Time))
+	if !ok {
+		return 0, false
+	}
+	return propVal * float32(r), true
+}
+
+// hpiValField returns the property value at the month of the row.  If there is no Hpi or the place/month isn't in
+// it, the return is nil so the field takes its default rather than failing validation.
+func hpiValField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	if v, ok := hpiValue(td, data, valid); ok {
+		return v, nil
+	}
+	return nil, nil
+}
+
+// eLtvField returns the mark-to-market LTV.  As with hpiValField, the return is nil if it can't be calculated.
+func eLtvField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	v, ok := hpiValue(td, data, valid)
+	upb, ok1 := value(td, data, valid, "upb")
+	if !ok || !ok1 || v <= 0 || upb.(float32) <= 0 {
+		return nil, nil
+	}
+	return 100.0 * upb.(float32) / v, nil
+}
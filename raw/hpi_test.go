@@ -0,0 +1,65 @@
+package raw
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+const testHPI = `hpi_type,hpi_flavor,frequency,level,place_name,place_id,yr,period,index_nsa,index_sa
+traditional,all-transactions,quarterly,MSA,A,10000,2020,1,200,
+traditional,all-transactions,quarterly,MSA,A,10000,2020,2,220,
+traditional,purchase-only,monthly,MSA,A,10000,2020,1,100,
+traditional,purchase-only,monthly,MSA,A,10000,2020,4,110,
+traditional,all-transactions,quarterly,MSA,B,20000,2020,1,50,
+traditional,all-transactions,quarterly,MSA,B,20000,2020,2,75,
+traditional,purchase-only,monthly,State,Texas,TX,2020,1,,300
+traditional,purchase-only,monthly,State,Texas,TX,2020,4,330,
+traditional,purchase-only,monthly,USA or Census Division,USA,USA,2020,1,1,
+`
+
+func TestReadHPI(t *testing.T) {
+	h, err := readHPI(strings.NewReader(testHPI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dt := func(mon time.Month) time.Time { return time.Date(2020, mon, 1, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		name  string
+		msa   string
+		state string
+		month time.Time
+		want  float64
+		ok    bool
+	}{
+		{"monthly takes precedence", "10000", "TX", dt(4), 1.1, true},
+		{"no monthly value", "10000", "TX", dt(5), 0, false},
+		{"quarterly spread over months", "20000", "TX", dt(5), 1.5, true},
+		{"msa falls back to state", "99999", "TX", dt(4), 1.1, true},
+		{"sa used if no nsa", "", "TX", dt(1), 1, true},
+		{"no msa or state", "99999", "CA", dt(4), 0, false},
+		{"other levels skipped", "USA", "USA", dt(1), 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := h.ratio(tt.msa, tt.state, dt(1), tt.month)
+		if ok != tt.ok || (ok && (got < tt.want-1e-9 || got > tt.want+1e-9)) {
+			t.Errorf("%s: ratio = %v, %v, want %v, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestReadHPIErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{"missing column", "level,place_id,frequency,yr,period\nMSA,10000,monthly,2020,1\n"},
+		{"bad year", "level,place_id,frequency,yr,period,index_nsa\nMSA,10000,monthly,20x0,1,100\n"},
+		{"bad index", "level,place_id,frequency,yr,period,index_nsa\nMSA,10000,monthly,2020,1,abc\n"},
+	}
+	for _, tt := range tests {
+		if _, err := readHPI(strings.NewReader(tt.data)); err == nil {
+			t.Errorf("%s: no error", tt.name)
+		}
+	}
+}
@@ -0,0 +1,195 @@
+package raw
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math/bits"
+	"strings"
+	"sync"
+)
+
+// JoinSpec specifies how a pair of files -- one with a row per loan of static fields and one with a row per loan
+// per month of monthly fields -- is joined and mapped to the sf2020 layout.  Both files are '|' delimited.
+type JoinSpec struct {
+	StaticCols  int            // StaticCols is the minimum # of columns in the static file
+	MonthlyCols int            // MonthlyCols is the minimum # of columns in the monthly file
+	StaticKey   int            // StaticKey is the column of the loan id in the static file
+	MonthlyKey  int            // MonthlyKey is the column of the loan id in the monthly file
+	StaticMap   map[int]int    // StaticMap maps columns of the static file to sf2020 columns
+	MonthlyMap  map[int]int    // MonthlyMap maps columns of the monthly file to sf2020 columns
+	Fix         func([]string) // Fix converts the values of the sf2020 columns to the sf2020 formats. Optional.
+}
+
+// sfColumns is the number of columns in the sf2020 layout
+const sfColumns = 108
+
+// staticCache holds the most recent static file read, since each reader of a monthly file needs it.
+var staticCache struct {
+	sync.Mutex
+	name   string
+	static map[string][]string
+}
+
+// loadStatic reads the static file into a map keyed by loan id.  The values are the columns of the file.
+func loadStatic(name string, spec *JoinSpec) (map[string][]string, error) {
+	staticCache.Lock()
+	defer staticCache.Unlock()
+	if staticCache.name == name {
+		return staticCache.static, nil
+	}
+
+	f, err := openPlain(name)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	static := make(map[string][]string)
+	rdr := bufio.NewReader(f)
+	for lineNo := 1; ; lineNo++ {
+		line, e := rdr.ReadString('\n')
+		if e != nil && e != io.EOF {
+			return nil, e
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			flds := strings.Split(line, "|")
+			if len(flds) < spec.StaticCols {
+				return nil, fmt.Errorf("file %s line %d has %d columns, expected at least %d", name, lineNo, len(flds), spec.StaticCols)
+			}
+			static[flds[spec.StaticKey]] = flds
+		}
+		if e == io.EOF {
+			break
+		}
+	}
+	staticCache.name, staticCache.static = name, static
+	return static, nil
+}
+
+// unmatched holds the lines of the monthly file of the current load that have no loan in the static file.  A line is
+// read more than once during a load (see NewRdrs), so the lines are a set rather than a count.
+var unmatched struct {
+	sync.Mutex
+	lines []uint64 // lines is a bit set of line #s
+}
+
+// Unmatched returns the # of rows of the last load of a joined file that have no loan in the static file.  The static
+// fields of these rows are empty.
+func Unmatched() int64 {
+	unmatched.Lock()
+	defer unmatched.Unlock()
+	n := 0
+	for _, w := range unmatched.lines {
+		n += bits.OnesCount64(w)
+	}
+	return int64(n)
+}
+
+// resetUnmatched clears the unmatched lines at the start of a load
+func resetUnmatched() {
+	unmatched.Lock()
+	unmatched.lines = nil
+	unmatched.Unlock()
+}
+
+// addUnmatched adds line to the unmatched lines
+func addUnmatched(line int) {
+	unmatched.Lock()
+	defer unmatched.Unlock()
+	for len(unmatched.lines) <= line/64 {
+		unmatched.lines = append(unmatched.lines, 0)
+	}
+	unmatched.lines[line/64] |= 1 << uint(line%64)
+}
+
+// Join is an io.ReadSeekCloser that presents the join of a static file and a monthly file in the sf2020 layout.
+// The static fields are added to each row of the monthly file. The static file is held in memory.
+type Join struct {
+	name   string              // name is the name of the monthly file
+	spec   *JoinSpec           // spec specifies the join
+	static map[string][]string // static is the static file keyed by loan id
+	rc     io.ReadSeekCloser   // rc is the monthly file
+	rdr    *bufio.Reader       // rdr reads lines from rc
+	buf    []byte              // buf is converted data not yet returned by Read
+	line   int                 // line is the current line # of the monthly file
+}
+
+// NewJoin opens the monthly file monthlyName and joins it to the static file staticName.
+func NewJoin(staticName string, monthlyName string, spec *JoinSpec) (*Join, error) {
+	static, err := loadStatic(staticName, spec)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := openPlain(monthlyName)
+	if err != nil {
+		return nil, err
+	}
+	return &Join{name: monthlyName, spec: spec, static: static, rc: rc, rdr: bufio.NewReader(rc)}, nil
+}
+
+func (j *Join) Read(p []byte) (int, error) {
+	for len(j.buf) == 0 {
+		line, err := j.rdr.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			j.line++
+			conv, e := j.convert(line)
+			if e != nil {
+				return 0, e
+			}
+			j.buf = append(j.buf, conv...)
+		}
+		if err == io.EOF && len(j.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, j.buf)
+	j.buf = j.buf[n:]
+	return n, nil
+}
+
+func (j *Join) Seek(offset int64, whence int) (int64, error) {
+	if offset != 0 || whence != io.SeekStart {
+		return 0, fmt.Errorf("joined files can only seek to the start")
+	}
+	if _, e := j.rc.Seek(offset, whence); e != nil {
+		return 0, e
+	}
+	j.rdr.Reset(j.rc)
+	j.buf, j.line = nil, 0
+	return 0, nil
+}
+
+func (j *Join) Close() error {
+	return j.rc.Close()
+}
+
+// convert converts a line of the monthly file to a line in the sf2020 layout
+func (j *Join) convert(line string) (string, error) {
+	monthly := strings.Split(line, "|")
+	if len(monthly) < j.spec.MonthlyCols {
+		return "", fmt.Errorf("file %s line %d has %d columns, expected at least %d", j.name, j.line, len(monthly), j.spec.MonthlyCols)
+	}
+	out := make([]string, sfColumns)
+	if static, ok := j.static[monthly[j.spec.MonthlyKey]]; ok {
+		for from, to := range j.spec.StaticMap {
+			if from < len(static) {
+				out[to] = static[from]
+			}
+		}
+	} else {
+		addUnmatched(j.line)
+	}
+	for from, to := range j.spec.MonthlyMap {
+		if from < len(monthly) {
+			out[to] = monthly[from]
+		}
+	}
+	if j.spec.Fix != nil {
+		j.spec.Fix(out)
+	}
+	return strings.Join(out, "|") + "\n", nil
+}
@@ -0,0 +1,101 @@
+package raw
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// joinRow returns a '|' delimited row of n columns with the values in vals
+func joinRow(n int, vals map[int]string) string {
+	flds := make([]string, n)
+	for ind, v := range vals {
+		flds[ind] = v
+	}
+	return strings.Join(flds, "|") + "\n"
+}
+
+func TestOpenLegacy(t *testing.T) {
+	dir := t.TempDir()
+	acq := joinRow(25, map[int]string{0: "100000000001", 1: "R", 3: "4.25", 7: "03/2010", 12: "760", 19: "752"})
+	perf := joinRow(29, map[int]string{0: "100000000001", 1: "06/01/2011", 4: "98000", 10: "2", 17: "-1500"}) +
+		joinRow(29, map[int]string{0: "100000000002", 1: "06/01/2011", 4: "50000"}) +
+		joinRow(29, map[int]string{0: "100000000002", 1: "07/01/2011", 4: "49000"})
+	files := map[string]string{"Acquisition_2010Q1.txt": acq, "Performance_2010Q1.txt": perf,
+		"Acquisition_2010Q2.txt": joinRow(10, nil), "Performance_2010Q2.txt": perf,
+		"Acquisition_2010Q3.txt": acq, "Performance_2010Q3.txt": joinRow(10, nil)}
+	for name, data := range files {
+		if e := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	if !isLegacy(filepath.Join(dir, "Performance_2010Q1.txt")) || isLegacy(filepath.Join(dir, "2010Q1.csv")) {
+		t.Error("isLegacy does not detect the Performance file")
+	}
+	for _, q := range []string{"Q2", "Q3"} {
+		rdr, err := openLegacy(filepath.Join(dir, "Performance_2010"+q+".txt"))
+		if err == nil {
+			_, err = io.ReadAll(rdr)
+			_ = rdr.Close()
+		}
+		if err == nil {
+			t.Errorf("2010%s: no error for a file with too few columns", q)
+		}
+	}
+
+	resetUnmatched()
+	rdr, err := openLegacy(filepath.Join(dir, "Performance_2010Q1.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = rdr.Close() }()
+	var b []byte
+	// rows read again after a Seek are counted once
+	for pass := 0; pass < 2; pass++ {
+		if _, e := rdr.Seek(0, io.SeekStart); e != nil {
+			t.Fatal(e)
+		}
+		if b, err = io.ReadAll(rdr); err != nil {
+			t.Fatal(err)
+		}
+	}
+	lines := strings.Split(strings.TrimRight(string(b), "\n"), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("got %d rows, want 3", len(lines))
+	}
+	if n := Unmatched(); n != 2 {
+		t.Errorf("Unmatched() = %d, want 2", n)
+	}
+
+	tests := []struct {
+		line int
+		col  int
+		want string
+	}{
+		{0, 1, "100000000001"}, // lnId
+		{0, 2, "062011"},       // month
+		{0, 3, "R"},            // channel
+		{0, 7, "4.25"},         // rate
+		{0, 11, "98000"},       // upb
+		{0, 14, "032010"},      // fpDt
+		{0, 23, "760"},         // fico
+		{0, 32, "752"},         // zip3
+		{0, 39, "02"},          // dqStat
+		{0, 53, "-1500"},       // fclExp
+		{1, 1, "100000000002"}, // lnId of the monthly file
+		{1, 3, ""},             // no Acquisition row
+		{2, 11, "49000"},       // upb
+	}
+	for _, tt := range tests {
+		out := strings.Split(lines[tt.line], "|")
+		if len(out) != sfColumns {
+			t.Fatalf("row %d has %d columns, want %d", tt.line, len(out), sfColumns)
+		}
+		if out[tt.col] != tt.want {
+			t.Errorf("row %d column %d = %q, want %q", tt.line, tt.col, out[tt.col], tt.want)
+		}
+	}
+}
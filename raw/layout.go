@@ -0,0 +1,136 @@
+package raw
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Layout is a version of the Fannie record layout.  New Fannie releases are supported by registering a Layout.
+type Layout struct {
+	Name    string                   // Name is the layout version, used by the -layout flag
+	Excl    bool                     // Excl is true if the layout is for non-standard (excluded) loans
+	CRT     bool                     // CRT is true if the layout is for CAS/CIRT credit risk transfer loans
+	Build   func() *chutils.TableDef // Build returns the TableDef of the fields in the file
+	Sniff   func([]string) bool      // Sniff returns true if a data row belongs to this layout. Optional.
+	columns int                      // columns is the # of columns in the file
+}
+
+// Columns returns the number of columns in files of this layout.
+func (l *Layout) Columns() int {
+	return l.columns
+}
+
+// layouts is the registry of layouts, keyed by Name
+var layouts = make(map[string]*Layout)
+
+// Register adds a layout to the registry.
+func Register(l *Layout) {
+	l.columns = len(l.Build().FieldDefs)
+	layouts[l.Name] = l
+}
+
+// GetLayout returns the layout with the given name.
+func GetLayout(name string) (*Layout, error) {
+	if l, ok := layouts[name]; ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("unknown layout %s, layouts are: %s", name, layoutList())
+}
+
+func init() {
+	// the reference pool ID is populated only in CAS/CIRT files
+	Register(&Layout{Name: "sf2020", Excl: false, Build: func() *chutils.TableDef { return build(false) },
+		Sniff: func(fields []string) bool { return fields[0] == "" }})
+	Register(&Layout{Name: "sf2020excl", Excl: true, Build: func() *chutils.TableDef { return build(true) }})
+	Register(&Layout{Name: "crt", CRT: true, Build: buildCRT,
+		Sniff: func(fields []string) bool { return fields[0] != "" }})
+}
+
+// Detect determines the layout of sourceFile, as opened by open, from the number of columns in its first line.
+// Layouts with the same number of columns are distinguished by their Sniff of the first data row.
+// If name is not empty, that layout is used and Detect checks that sourceFile matches it.
+// Detect also returns the # of header rows.
+func Detect(sourceFile string, name string, open Opener) (l *Layout, skip int, err error) {
+	f, err := open(sourceFile)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = f.Close() }()
+
+	rdr := bufio.NewReader(f)
+	fields, err := readFields(rdr)
+	if err != nil {
+		return nil, 0, err
+	}
+	if isHeader(fields) {
+		skip = 1
+		if fields, err = readFields(rdr); err != nil {
+			return nil, 0, err
+		}
+	}
+	cols := len(fields)
+
+	if name != "" {
+		if l, err = GetLayout(name); err != nil {
+			return nil, 0, err
+		}
+		if l.Columns() != cols {
+			return nil, 0, fmt.Errorf("file %s has %d columns, layout %s expects %d", sourceFile, cols, l.Name, l.Columns())
+		}
+		return l, skip, nil
+	}
+
+	matches := make([]string, 0)
+	for _, lx := range layouts {
+		if lx.Columns() == cols && (lx.Sniff == nil || lx.Sniff(fields)) {
+			matches = append(matches, lx.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return nil, 0, fmt.Errorf("file %s has %d columns, no layout matches. Expected one of: %s", sourceFile, cols, layoutList())
+	case 1:
+		return layouts[matches[0]], skip, nil
+	}
+	sort.Strings(matches)
+	return nil, 0, fmt.Errorf("file %s has %d columns which matches layouts %s. Use -layout to choose one",
+		sourceFile, cols, strings.Join(matches, ", "))
+}
+
+// readFields reads the next line and splits it into fields
+func readFields(rdr *bufio.Reader) ([]string, error) {
+	line, err := rdr.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+	return strings.Split(strings.TrimRight(line, "\r\n"), "|"), nil
+}
+
+// isHeader returns true if the line is a header row.  Data rows always have numeric fields (lnId, month, etc.)
+func isHeader(fields []string) bool {
+	for _, fld := range fields {
+		if _, e := strconv.ParseFloat(fld, 64); e == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// layoutList returns the registered layouts and their column counts.
+func layoutList() string {
+	names := make([]string, 0)
+	for name := range layouts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	list := make([]string, 0)
+	for _, name := range names {
+		list = append(list, fmt.Sprintf("%s (%d columns)", name, layouts[name].Columns()))
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,111 @@
+package raw
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+// stringFile is an in-memory source file
+type stringFile struct {
+	*strings.Reader
+}
+
+func (s stringFile) Close() error {
+	return nil
+}
+
+// openString returns an Opener that opens the in-memory files of files
+func openString(files map[string]string) Opener {
+	return func(source string) (io.ReadSeekCloser, error) {
+		if f, ok := files[source]; ok {
+			return stringFile{strings.NewReader(f)}, nil
+		}
+		return nil, fmt.Errorf("no file %s", source)
+	}
+}
+
+// row returns a data row of cols columns whose first field is first
+func row(cols int, first string) string {
+	fields := make([]string, cols)
+	fields[0] = first
+	for ind := 1; ind < cols; ind++ {
+		fields[ind] = "1"
+	}
+	return strings.Join(fields, "|") + "\n"
+}
+
+// header returns a header row of cols columns
+func header(cols int) string {
+	fields := make([]string, cols)
+	for ind := range fields {
+		fields[ind] = fmt.Sprintf("f%d", ind)
+	}
+	return strings.Join(fields, "|") + "\n"
+}
+
+func TestGetLayout(t *testing.T) {
+	tests := []struct {
+		name    string
+		cols    int
+		wantErr bool
+	}{
+		{"sf2020", 108, false},
+		{"sf2020excl", 112, false},
+		{"crt", 108, false},
+		{"sf2019", 0, true},
+	}
+	for _, tt := range tests {
+		l, err := GetLayout(tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("GetLayout(%s) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err == nil && (l.Name != tt.name || l.Columns() != tt.cols) {
+			t.Errorf("GetLayout(%s) = %s with %d columns, want %d columns", tt.name, l.Name, l.Columns(), tt.cols)
+		}
+	}
+}
+
+func TestDetect(t *testing.T) {
+	files := map[string]string{
+		"sf.csv":       row(108, ""),
+		"crt.csv":      row(108, "CAS 2020 R01"),
+		"excl.csv":     row(112, ""),
+		"header.csv":   header(112) + row(112, ""),
+		"short.csv":    row(50, ""),
+		"headOnly.csv": header(108),
+	}
+	tests := []struct {
+		file    string
+		name    string
+		want    string
+		skip    int
+		wantErr bool
+	}{
+		{"sf.csv", "", "sf2020", 0, false},
+		{"crt.csv", "", "crt", 0, false},
+		{"excl.csv", "", "sf2020excl", 0, false},
+		{"header.csv", "", "sf2020excl", 1, false},
+		{"short.csv", "", "", 0, true},
+		{"sf.csv", "crt", "crt", 0, false},
+		{"sf.csv", "sf2020excl", "", 0, true},
+		{"sf.csv", "sf2019", "", 0, true},
+		{"headOnly.csv", "", "", 0, true},
+		{"missing.csv", "", "", 0, true},
+	}
+	for _, tt := range tests {
+		l, skip, err := Detect(tt.file, tt.name, openString(files))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Detect(%s, %q) error = %v, wantErr %v", tt.file, tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if l.Name != tt.want || skip != tt.skip {
+			t.Errorf("Detect(%s, %q) = %s, %d, want %s, %d", tt.file, tt.name, l.Name, skip, tt.want, tt.skip)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package raw
+
+import (
+	"io"
+	"strings"
+)
+
+// legacySpec specifies the join of the Acquisition and Performance files.  Later releases added columns at the end.
+var legacySpec = &JoinSpec{
+	StaticCols:  24,
+	MonthlyCols: 29,
+	StaticKey:   0,
+	MonthlyKey:  0,
+	StaticMap: map[int]int{
+		0:  1,  // LOAN_ID -> lnId
+		1:  3,  // ORIG_CHN -> channel
+		2:  4,  // Seller.Name -> seller
+		3:  7,  // ORIG_RT -> rate
+		4:  9,  // ORIG_AMT -> opb
+		5:  12, // ORIG_TRM -> term
+		6:  13, // ORIG_DTE -> origDt
+		7:  14, // FRST_DTE -> fpDt
+		8:  19, // OLTV -> ltv
+		9:  20, // OCLTV -> cltv
+		10: 21, // NUM_BO -> numBorr
+		11: 22, // DTI -> dti
+		12: 23, // CSCORE_B -> fico
+		13: 25, // FTHB_FLG -> firstTime
+		14: 26, // PURPOSE -> purpose
+		15: 27, // PROP_TYP -> propType
+		16: 28, // NUM_UNIT -> units
+		17: 29, // OCC_STAT -> occ
+		18: 30, // STATE -> state
+		19: 32, // ZIP_3 -> zip3
+		20: 33, // MI_PCT -> mi
+		21: 34, // Product.Type -> amType
+		22: 24, // CSCORE_C -> coFico
+		23: 72, // MI_TYPE -> miType
+		24: 80, // RELOCATION_FLG -> relo
+	},
+	MonthlyMap: map[int]int{
+		0:  1,   // LOAN_ID -> lnId
+		1:  2,   // Monthly.Rpt.Prd -> month
+		2:  5,   // Servicer.Name -> servicer
+		3:  8,   // LAST_RT -> curRate
+		4:  11,  // LAST_UPB -> upb
+		5:  15,  // Loan.Age -> age
+		6:  16,  // Months.To.Legal.Mat -> rTermLgl
+		7:  17,  // Adj.Month.To.Mat -> rTermAct
+		8:  18,  // Maturity.Date -> matDt
+		9:  31,  // MSA -> msa
+		10: 39,  // Delq.Status -> dqStat
+		11: 41,  // MOD_FLAG -> mod
+		12: 43,  // Zero.Bal.Code -> zb
+		13: 44,  // ZB_DTE -> zbDt
+		14: 50,  // LPI_DTE -> lpDt
+		15: 51,  // FCC_DTE -> fclDt
+		16: 52,  // DISP_DT -> dispDt
+		17: 53,  // FCC_COST -> fclExp
+		18: 54,  // PP_COST -> fclPExp
+		19: 55,  // AR_COST -> fclLExp
+		20: 56,  // IE_COST -> fclMExp
+		21: 57,  // TAX_COST -> fclTaxes
+		22: 58,  // NS_PROCS -> fclProNet
+		23: 59,  // CE_PROCS -> fclProMi
+		24: 60,  // RMW_PROCS -> fclProMw
+		25: 61,  // O_PROCS -> fclProOth
+		26: 62,  // NON_INT_UPB -> nonIntUpb
+		27: 63,  // PRIN_FORG_UPB_FHFA -> frgvUpb
+		28: 104, // REPCH_FLAG -> reprchMw
+	},
+	Fix: legacyFix,
+}
+
+// legacyDates are the sf2020 columns that are dates.  The legacy files have these as MM/YYYY or MM/DD/YYYY,
+// the sf2020 layout has MMYYYY.
+var legacyDates = []int{2, 13, 14, 18, 44, 50, 51, 52}
+
+// legacyFix converts the legacy formats to the sf2020 formats.
+func legacyFix(out []string) {
+	for _, ind := range legacyDates {
+		if parts := strings.Split(out[ind], "/"); len(parts) > 1 {
+			out[ind] = parts[0] + parts[len(parts)-1]
+		}
+	}
+	// the legacy files don't have a leading 0 on the delinquency status
+	if len(out[39]) == 1 && out[39][0] >= '0' && out[39][0] <= '9' {
+		out[39] = "0" + out[39]
+	}
+}
+
+// isLegacy returns true if source is a legacy Performance file
+func isLegacy(source string) bool {
+	return strings.HasPrefix(BaseName(source), "Performance_")
+}
+
+// openLegacy opens the legacy Performance file perfName joined to its Acquisition file.
+//
+// Legacy (pre-2020) Fannie releases have two files per quarter:
+//   - Acquisition_YYYYQn.txt.  One row per loan with the static fields.
+//   - Performance_YYYYQn.txt.  One row per loan per month with the monthly fields.
+//
+// The Acquisition file must be in the same directory (or .zip archive) and have the same compression.
+// Fields not in the legacy files are left empty, so they take on their default (or missing) values.  Performance rows
+// with no loan in the Acquisition file have empty static fields.  These are counted by Unmatched.
+func openLegacy(perfName string) (io.ReadSeekCloser, error) {
+	ind := strings.LastIndex(perfName, "Performance_")
+	acqName := perfName[:ind] + "Acquisition_" + perfName[ind+len("Performance_"):]
+	return NewJoin(acqName, perfName, legacySpec)
+}
@@ -0,0 +1,120 @@
+package raw
+
+import (
+	"bufio"
+	_ "embed"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"io"
+	"strings"
+	"sync/atomic"
+)
+
+// crosswalkData is the default MSA crosswalk
+//
+//go:embed data/msa_crosswalk.csv
+var crosswalkData string
+
+// Crosswalk maps deprecated MSA/CBSA codes to their current codes.
+type Crosswalk struct {
+	Version string            // Version is the version of the crosswalk, from the "# version:" line of the file
+	Map     map[string]string // Map maps the deprecated code to the current code
+}
+
+// MsaCrosswalk is the crosswalk applied to the msa field during the load.  The default is embedded in the package.
+// It is set before the init funcs run, since these build TableDefs.
+var MsaCrosswalk = defaultCrosswalk()
+
+// remapped is the # of msa values remapped during the current load
+var remapped int64
+
+// defaultCrosswalk reads the embedded crosswalk
+func defaultCrosswalk() *Crosswalk {
+	cw, err := readCrosswalk(strings.NewReader(crosswalkData))
+	if err != nil {
+		panic(err)
+	}
+	return cw
+}
+
+// LoadCrosswalk replaces the default MSA crosswalk with the one in sourceFile.
+//
+// The file is comma delimited with two columns: the deprecated code and the current code.  Lines beginning with
+// # are comments, except "# version: <version>" gives the version.  An optional header row is skipped.
+func LoadCrosswalk(sourceFile string) error {
+	f, err := Open(sourceFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	cw, err := readCrosswalk(f)
+	if err != nil {
+		return fmt.Errorf("crosswalk %s: %v", sourceFile, err)
+	}
+	MsaCrosswalk = cw
+	return nil
+}
+
+// Remapped returns the # of msa values the crosswalk remapped during the last load.
+func Remapped() int64 {
+	return atomic.LoadInt64(&remapped)
+}
+
+// readCrosswalk reads a crosswalk file
+func readCrosswalk(r io.Reader) (*Crosswalk, error) {
+	cw := &Crosswalk{Version: "unknown", Map: make(map[string]string)}
+	scan := bufio.NewScanner(r)
+	for lineNo := 1; scan.Scan(); lineNo++ {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if v := strings.TrimSpace(strings.TrimPrefix(line, "#")); strings.HasPrefix(v, "version:") {
+				cw.Version = strings.TrimSpace(strings.TrimPrefix(v, "version:"))
+			}
+			continue
+		}
+		flds := strings.Split(line, ",")
+		if len(flds) != 2 {
+			return nil, fmt.Errorf("line %d has %d columns, expected 2", lineNo, len(flds))
+		}
+		oldMsa, newMsa := strings.TrimSpace(flds[0]), strings.TrimSpace(flds[1])
+		// header row
+		if oldMsa == "oldMsa" {
+			continue
+		}
+		if len(oldMsa) != 5 || len(newMsa) != 5 {
+			return nil, fmt.Errorf("line %d: msa codes must have 5 digits", lineNo)
+		}
+		cw.Map[oldMsa] = newMsa
+	}
+	if err := scan.Err(); err != nil {
+		return nil, err
+	}
+	return cw, nil
+}
+
+// codes returns the codes in the crosswalk: the deprecated codes and the current codes they map to
+func (cw *Crosswalk) codes() []string {
+	seen := make(map[string]bool)
+	codes := make([]string, 0, 2*len(cw.Map))
+	for oldMsa, newMsa := range cw.Map {
+		for _, c := range []string{oldMsa, newMsa} {
+			if !seen[c] {
+				seen[c] = true
+				codes = append(codes, c)
+			}
+		}
+	}
+	return codes
+}
+
+// msaOrigField returns the msa as it appears in the file, before the crosswalk is applied
+func msaOrigField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	ind, _, err := td.Get("msa")
+	if err != nil {
+		return nil, err
+	}
+	return data[ind], nil
+}
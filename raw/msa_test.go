@@ -0,0 +1,48 @@
+package raw
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestReadCrosswalk(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		version string
+		want    map[string]string
+		wantErr bool
+	}{
+		{"header and version", "# version: 2023\noldMsa,newMsa\n12345,54321\n\n 11111 , 22222 \n", "2023",
+			map[string]string{"12345": "54321", "11111": "22222"}, false},
+		{"no version", "# comment\n12345,54321\n", "unknown", map[string]string{"12345": "54321"}, false},
+		{"empty", "", "unknown", map[string]string{}, false},
+		{"three columns", "12345,54321,99999\n", "", nil, true},
+		{"short code", "1234,54321\n", "", nil, true},
+	}
+	for _, tt := range tests {
+		cw, err := readCrosswalk(strings.NewReader(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if cw.Version != tt.version || !reflect.DeepEqual(cw.Map, tt.want) {
+			t.Errorf("%s: got version %s map %v, want %s %v", tt.name, cw.Version, cw.Map, tt.version, tt.want)
+		}
+	}
+}
+
+func TestCrosswalkCodes(t *testing.T) {
+	cw := &Crosswalk{Map: map[string]string{"11111": "33333", "22222": "33333", "44444": "11111"}}
+	got := cw.codes()
+	sort.Strings(got)
+	want := []string{"11111", "22222", "33333", "44444"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("codes() = %v, want %v", got, want)
+	}
+}
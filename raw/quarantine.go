@@ -0,0 +1,135 @@
+package raw
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"strings"
+	"sync"
+)
+
+// Fatal are the fields whose failure sends a row to quarantine rather than the output table.
+var Fatal = []string{"lnId", "month"}
+
+// Quarantined is a row that failed a Fatal field.
+type Quarantined struct {
+	File   string   // File is the source file
+	Line   int      // Line is the line # of the row in the source file
+	Text   string   // Text is the row as read, '|' delimited
+	Fields []string // Fields are the Fatal fields that failed
+}
+
+// quarantineList holds the rows quarantined by the current load
+type quarantineList struct {
+	sync.Mutex
+	rows []*Quarantined
+}
+
+// quarantine holds the rows quarantined by the current load
+var quarantine = &quarantineList{}
+
+// Quarantine returns the rows quarantined by the last load.
+func Quarantine() []*Quarantined {
+	quarantine.Lock()
+	defer quarantine.Unlock()
+	return quarantine.rows
+}
+
+// reset empties the list
+func (q *quarantineList) reset() {
+	q.Lock()
+	defer q.Unlock()
+	q.rows = make([]*Quarantined, 0)
+}
+
+// add adds a row to the list
+func (q *quarantineList) add(raw chutils.Row, line int, failed []string) {
+	text := make([]string, 0, len(raw))
+	for _, v := range raw {
+		text = append(text, fmt.Sprintf("%v", v))
+	}
+	q.Lock()
+	defer q.Unlock()
+	q.rows = append(q.rows, &Quarantined{File: fileName, Line: line, Text: strings.Join(text, "|"), Fields: failed})
+}
+
+// SetFatal sets Fatal to fields.  It is an error if a field is not read from the source files.
+func SetFatal(fields []string) error {
+	if _, err := fatalInds(build(true), fields); err != nil {
+		return err
+	}
+	Fatal = fields
+	return nil
+}
+
+// fatalInds returns the indices in td of the fatal fields.  It is an error if a field is not in td.
+func fatalInds(td *chutils.TableDef, fatal []string) ([]int, error) {
+	inds := make([]int, 0)
+	unknown := make([]string, 0)
+	for _, name := range fatal {
+		ind, _, err := td.Get(name)
+		if err != nil {
+			unknown = append(unknown, name)
+			continue
+		}
+		inds = append(inds, ind)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("unknown fatal fields: %s", strings.Join(unknown, ", "))
+	}
+	return inds, nil
+}
+
+// fatalFail returns the names of the fatal fields that failed.  A fatal field that is empty in the file fails.
+func fatalFail(td *chutils.TableDef, fatal []int, raw chutils.Row, valid chutils.Valid) []string {
+	failed := make([]string, 0)
+	for _, ind := range fatal {
+		if v := valid[ind]; (v != chutils.VPass && v != chutils.VDefault) || raw[ind] == "" {
+			failed = append(failed, td.FieldDefs[ind].Name)
+		}
+	}
+	return failed
+}
+
+// CreateQuarantine creates the quarantine table if it does not exist.
+func CreateQuarantine(table string, con *chutils.Connect) error {
+	_, err := con.Exec(fmt.Sprintf(quarantineQry, table))
+	return err
+}
+
+// WriteQuarantine replaces the rows for sourceFile in the quarantine table with rows.
+func WriteQuarantine(table string, sourceFile string, rows []*Quarantined, con *chutils.Connect) error {
+	qry := fmt.Sprintf("ALTER TABLE %s DELETE WHERE file = ? SETTINGS mutations_sync = 1", table)
+	if _, err := con.Exec(qry, sourceFile); err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+	tx, err := con.Begin()
+	if err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(fmt.Sprintf("INSERT INTO %s VALUES (?, ?, ?, ?)", table))
+	if err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	for _, r := range rows {
+		if _, e := stmt.Exec(sourceFile, int64(r.Line), r.Text, r.Fields); e != nil {
+			_ = tx.Rollback()
+			return e
+		}
+	}
+	return tx.Commit()
+}
+
+// quarantineQry creates the quarantine table.  There is a placeholder for the table name.
+const quarantineQry = `
+CREATE TABLE IF NOT EXISTS %s (
+  file   String        COMMENT 'source file',
+  line   Int64         COMMENT 'line # in the source file',
+  text   String        COMMENT 'row as read, | delimited',
+  fields Array(String) COMMENT 'fatal fields that failed'
+) ENGINE=MergeTree()
+ORDER BY (file, line)
+`
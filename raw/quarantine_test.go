@@ -0,0 +1,84 @@
+package raw
+
+import (
+	"github.com/invertedv/chutils"
+	"reflect"
+	"testing"
+)
+
+func TestFatalInds(t *testing.T) {
+	td := build(false)
+	ind := func(name string) int {
+		i, _, _ := td.Get(name)
+		return i
+	}
+	tests := []struct {
+		fatal   []string
+		want    []int
+		wantErr bool
+	}{
+		{[]string{"lnId", "month"}, []int{ind("lnId"), ind("month")}, false},
+		{[]string{}, []int{}, false},
+		{[]string{"lnId", "nope"}, nil, true},
+		{[]string{"nope", "nada"}, nil, true},
+	}
+	for _, tt := range tests {
+		got, err := fatalInds(td, tt.fatal)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("fatalInds(%v) error = %v, wantErr %v", tt.fatal, err, tt.wantErr)
+			continue
+		}
+		if err == nil && !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("fatalInds(%v) = %v, want %v", tt.fatal, got, tt.want)
+		}
+	}
+	if _, err := fatalInds(td, []string{"nope", "nada"}); err == nil || err.Error() != "unknown fatal fields: nope, nada" {
+		t.Errorf("fatalInds error = %v, want the unknown fields listed", err)
+	}
+}
+
+func TestFatalFail(t *testing.T) {
+	td := build(false)
+	fatal, err := fatalInds(td, []string{"lnId", "month"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	lnInd, monthInd := fatal[0], fatal[1]
+	tests := []struct {
+		name   string
+		raw    map[int]string
+		status map[int]chutils.Status
+		want   []string
+	}{
+		{"pass", map[int]string{lnInd: "1", monthInd: "012020"}, map[int]chutils.Status{lnInd: chutils.VPass, monthInd: chutils.VPass},
+			[]string{}},
+		{"fail", map[int]string{lnInd: "1", monthInd: "132020"}, map[int]chutils.Status{lnInd: chutils.VPass, monthInd: chutils.VValueFail},
+			[]string{"month"}},
+		{"default", map[int]string{lnInd: "1", monthInd: "x"}, map[int]chutils.Status{lnInd: chutils.VPass, monthInd: chutils.VDefault},
+			[]string{}},
+		{"empty", map[int]string{lnInd: "", monthInd: ""}, map[int]chutils.Status{lnInd: chutils.VPass, monthInd: chutils.VDefault},
+			[]string{"lnId", "month"}},
+	}
+	for _, tt := range tests {
+		raw, valid := make(chutils.Row, len(td.FieldDefs)), make(chutils.Valid, len(td.FieldDefs))
+		for ind, v := range tt.raw {
+			raw[ind] = v
+		}
+		for ind, s := range tt.status {
+			valid[ind] = s
+		}
+		if got := fatalFail(td, fatal, raw, valid); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: fatalFail = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSetFatal(t *testing.T) {
+	defer func(f []string) { Fatal = f }(Fatal)
+	if err := SetFatal([]string{"lnId", "nope"}); err == nil {
+		t.Error("SetFatal accepted an unknown field")
+	}
+	if err := SetFatal([]string{"lnId", "ltv"}); err != nil || !reflect.DeepEqual(Fatal, []string{"lnId", "ltv"}) {
+		t.Errorf("SetFatal error = %v, Fatal = %v", err, Fatal)
+	}
+}
@@ -0,0 +1,129 @@
+package raw
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/invertedv/chutils"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MarketRates are the monthly market mortgage rates, keyed by monthKey.  If nil, sato and incentive are missing.
+var MarketRates map[int]float32
+
+// spreadMiss is the missing (and default) value of sato and incentive
+const spreadMiss = float32(-99.0)
+
+// rateColumn is the header of the rate column of a rate file
+const rateColumn = "pmms30"
+
+// rateDateFormats are the date formats accepted in a rate file
+var rateDateFormats = []string{"2006-01-02", "1/2/2006", "01/02/2006", "2006-01", "200601"}
+
+// LoadRates loads the market mortgage rates in sourceFile into MarketRates.
+//
+// The file is a PMMS-style comma delimited file with a header.  The first column is the date.  The rate is the
+// pmms30 column, which is required.  Weekly rates are averaged over the month.
+func LoadRates(sourceFile string) error {
+	f, err := Open(sourceFile)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+	rates, err := readRates(f)
+	if err != nil {
+		return fmt.Errorf("rates %s: %v", sourceFile, err)
+	}
+	MarketRates = rates
+	return nil
+}
+
+// readRates reads a rate file
+func readRates(r io.Reader) (map[int]float32, error) {
+	rdr := csv.NewReader(r)
+	rdr.FieldsPerRecord = -1
+	header, err := rdr.Read()
+	if err != nil {
+		return nil, err
+	}
+	col := -1
+	for ind, name := range header {
+		if strings.ToLower(strings.TrimSpace(name)) == rateColumn {
+			col = ind
+		}
+	}
+	if col < 0 {
+		return nil, fmt.Errorf("no %s column in header", rateColumn)
+	}
+
+	sums, cnts := make(map[int]float64), make(map[int]int)
+	for lineNo := 2; ; lineNo++ {
+		row, e := rdr.Read()
+		if e == io.EOF {
+			break
+		}
+		if e != nil {
+			return nil, e
+		}
+		if len(row) <= col || strings.TrimSpace(row[col]) == "" {
+			continue
+		}
+		dt, e := parseRateDate(strings.TrimSpace(row[0]))
+		if e != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNo, e)
+		}
+		rate, e := strconv.ParseFloat(strings.TrimSpace(row[col]), 64)
+		if e != nil {
+			return nil, fmt.Errorf("line %d: bad rate %s", lineNo, row[col])
+		}
+		sums[monthKey(dt)] += rate
+		cnts[monthKey(dt)]++
+	}
+
+	rates := make(map[int]float32)
+	for key, sum := range sums {
+		rates[key] = float32(sum / float64(cnts[key]))
+	}
+	return rates, nil
+}
+
+// parseRateDate parses the date of a rate file
+func parseRateDate(dt string) (time.Time, error) {
+	for _, format := range rateDateFormats {
+		if t, err := time.Parse(format, dt); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("bad date %s", dt)
+}
+
+// spread returns the rate in the field rateName less the market rate in the month of the field dtName.  If there
+// are no MarketRates or the month isn't in them, the return is nil so the field takes its default rather than failing
+// validation.
+func spread(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, rateName string, dtName string) interface{} {
+	if MarketRates == nil {
+		return nil
+	}
+	rate, ok1 := value(td, data, valid, rateName)
+	dt, ok2 := value(td, data, valid, dtName)
+	if !ok1 || !ok2 {
+		return nil
+	}
+	mkt, ok := MarketRates[monthKey(dt.(time.Time))]
+	if !ok {
+		return nil
+	}
+	return rate.(float32) - mkt
+}
+
+// satoField returns the spread at origination: rate less the market rate at origDt
+func satoField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return spread(td, data, valid, "rate", "origDt"), nil
+}
+
+// incentiveField returns the refinance incentive: curRate less the market rate at month
+func incentiveField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return spread(td, data, valid, "curRate", "month"), nil
+}
@@ -0,0 +1,48 @@
+package raw
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadRates(t *testing.T) {
+	key := func(yr int, mon time.Month) int { return monthKey(time.Date(yr, mon, 1, 0, 0, 0, 0, time.UTC)) }
+	tests := []struct {
+		name    string
+		data    string
+		want    map[int]float32
+		wantErr bool
+	}{
+		{"weekly averaged", "date,pmms30\n2020-01-02,3.5\n2020-01-09,3.7\n2020-02-06,3.4\n",
+			map[int]float32{key(2020, 1): 3.6, key(2020, 2): 3.4}, false},
+		{"rate column not first", "week,pmms15,PMMS30\n1/2/2020,3.0,3.5\n01/09/2020,3.1,3.7\n",
+			map[int]float32{key(2020, 1): 3.6}, false},
+		{"monthly dates", "date,pmms30\n2020-01,3.5\n202002,3.4\n", map[int]float32{key(2020, 1): 3.5, key(2020, 2): 3.4},
+			false},
+		{"blank rates skipped", "date,pmms30\n2020-01-02,3.5\n2020-01-09,\n", map[int]float32{key(2020, 1): 3.5}, false},
+		{"no pmms30", "date,rate\n2020-01-02,3.5\n", nil, true},
+		{"bad date", "date,pmms30\nJan 2 2020,3.5\n", nil, true},
+		{"bad rate", "date,pmms30\n2020-01-02,abc\n", nil, true},
+	}
+	for _, tt := range tests {
+		got, err := readRates(strings.NewReader(tt.data))
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+			continue
+		}
+		for k, v := range tt.want {
+			if r, ok := got[k]; !ok || r < v-1e-5 || r > v+1e-5 {
+				t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+				break
+			}
+		}
+	}
+}
@@ -1,12 +1,22 @@
-// Package raw reads in the raw data -- either the standard or non-standard files.
+// Package raw reads in the raw data -- either the standard, non-standard or CAS/CIRT files.
+// The CAS/CIRT files populate the reference pool, deal name and loss-sharing fields, which are
+// none/missing for loans from the other files.
 // The package also adds a handful of new fields.
 //
+//   - agency.   FNMA or FHLMC.
 //   - qa.       Results of QA.  The string field lists every field that failed QA separated by colons.
+//     Failures of the cross-field Rules are listed as xf:<rule name>.
 //   - file.     Name of the source file.
 //   - dq.       Numeric delinquency level.
 //   - vintage.  Vintage of the loan based on the first pay date. The string format is CCYY"Q"q, for example 2020Q1.
 //   - propVal.  Property value at origination calculated from original balance and LTV.
 //   - standard. Flag that is Y if the loan is a standard loan.
+//   - msaOrig.  The msa as reported.  Deprecated MSA codes in msa are replaced by their current codes using
+//     MsaCrosswalk.
+//   - hpiVal.   Property value at the month: propVal indexed from fpDt by Hpi (see LoadHPI).
+//   - eLtv.     Mark-to-market LTV from upb and hpiVal.
+//   - sato.     Spread at origination: rate less the market rate (see LoadRates).
+//   - incentive. Refinance incentive: curRate less the market rate.
 //
 // The output table is <tmp>.source where tmp is the tmp DB specified on the command line
 package raw
@@ -17,8 +27,9 @@ import (
 	"github.com/invertedv/chutils/file"
 	"github.com/invertedv/chutils/nested"
 	s "github.com/invertedv/chutils/sql"
-	"os"
+	"io"
 	"strconv"
+	"sync/atomic"
 	"time"
 )
 
@@ -28,27 +39,56 @@ var TableDef *chutils.TableDef
 // Excl is true if this is a non-standard file
 var Excl bool
 
+// Opener opens a source for reading
+type Opener func(source string) (io.ReadSeekCloser, error)
+
+// bufSize is the buffer size of the file readers
+const bufSize = 100000000
+
 func init() {
-	// builds the TableDef for the raw files -- used by collapse.go
-	TableDef = build(true)
-	fds := TableDef.FieldDefs
+	TableDef = tableDef()
+}
+
+// tableDef builds the TableDef for the raw files -- used by collapse.go
+func tableDef() *chutils.TableDef {
+	td := build(true)
+	fds := td.FieldDefs
 	next := len(fds)
-	for _, fd := range xtraFields(false) {
+	for _, fd := range xtraFields(false, false) {
 		fds[next] = fd
 		next++
 	}
+	return td
 }
 
-func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chutils.Connect) (err error) {
-	// fileName is package global
-	fileName = sourceFile
+// LoadRaw loads the Fannie file sourceFile into table.  sourceFile may be a text file, a .gz or .zst compressed file
+// or a member of a .zip archive (see Members).
+// The record layout of sourceFile is detected from the file unless layout is not empty (see Detect).
+func LoadRaw(sourceFile string, table string, layout string, create bool, nConcur int, con *chutils.Connect) error {
+	return Load(sourceFile, table, layout, "FNMA", Open, create, nConcur, con)
+}
+
+// Load loads sourceFile into table. The source is opened with open, which presents the data in the layout.
+// agency is the value of the agency field.
+func Load(sourceFile string, table string, layout string, agencyName string, open Opener, create bool, nConcur int,
+	con *chutils.Connect) (err error) {
+	// fileName, agency are package globals
+	fileName, agency = sourceFile, agencyName
+	atomic.StoreInt64(&remapped, 0)
 
-	f, err := os.Open(fileName)
+	lay, skip, err := Detect(fileName, layout, open)
 	if err != nil {
 		return err
 	}
-	rdr := file.NewReader(fileName, '|', '\n', '"', 0, 0, 0, f, 100000000)
-	rdr.Skip = 0
+	// Detect reads the start of the file
+	resetUnmatched()
+	Excl = lay.Excl
+
+	f, err := open(fileName)
+	if err != nil {
+		return err
+	}
+	rdr := file.NewReader(fileName, '|', '\n', '"', 0, skip, 0, f, bufSize)
 	defer func() {
 		// don't throw an error if we already have one
 		if e := rdr.Close(); e != nil && err == nil {
@@ -56,21 +96,12 @@ func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chu
 		}
 	}()
 	// rdr is the base reader the slice of readers is based on
-	Excl = false
-	rdr.SetTableSpec(build(Excl))
-
-	// if this is an exclusion (non-standard) file, then this will produce an error
-	_, _, e := rdr.Read(1, true)
-	if e != nil {
-		Excl = true
-		rdr.SetTableSpec(build(Excl))
-	}
-	if e := rdr.Reset(); e != nil {
-		return e
-	}
+	rdr.SetTableSpec(lay.Build())
+	summary.reset(rdr.TableSpec())
+	quarantine.reset()
 
 	// build slice of readers. Note: chutils.Concur will close these.
-	rdrs, err := file.Rdrs(rdr, nConcur)
+	rdrs, err := NewRdrs(rdr, nConcur, bufSize, open)
 	if err != nil {
 		return
 	}
@@ -86,14 +117,22 @@ func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chu
 	if !Excl {
 		newCalcs = append(newCalcs, nsDocField, nsUwField, gGuarField, negAmField)
 	}
+	// fields that are only in CAS/CIRT files.  With Full, the cumulative losses are read from the file.
+	if !lay.CRT {
+		newCalcs = append(newCalcs, refPoolField, dealNameField)
+		if !Full {
+			newCalcs = append(newCalcs, modLossCumField, ceLossCumField)
+		}
+	}
 	// new fields
-	newCalcs = append(newCalcs, fField, dqField, vintField, pvField, stdField, vField)
+	newCalcs = append(newCalcs, fField, dqField, vintField, pvField, stdField, agencyField, msaOrigField, hpiValField, eLtvField, satoField, incentiveField, vField)
 
 	// rdrsn is a slice of nested readers -- needed since we are adding fields to the raw data
 	rdrsn := make([]chutils.Input, 0)
 	for j, r := range rdrs {
 
-		rn, e := nested.NewReader(r, xtraFields(Excl), newCalcs)
+		// tallyReader collects the validation summary (see Summaries)
+		rn, e := nested.NewReader(&tallyReader{r}, xtraFields(Excl, lay.CRT), newCalcs)
 		if e != nil {
 			return e
 		}
@@ -115,12 +154,21 @@ func LoadRaw(sourceFile string, table string, create bool, nConcur int, con *chu
 }
 
 // xtraFields defines additional fields for the nested reader
-func xtraFields(excl bool) []*chutils.FieldDef {
+func xtraFields(excl bool, crtFile bool) []*chutils.FieldDef {
 	// if the file is not excluded loans, then we need to add the fields that are not in the standard loan files
 	fds := make([]*chutils.FieldDef, 0)
 	if !excl {
 		fds = append(fds, excluded()...)
 	}
+	// if the file is not a CRT file, then we need to add the fields that are only populated in CRT files.  With
+	// Full, the cumulative losses are read from the file (see build).
+	if !crtFile {
+		if Full {
+			fds = append(fds, crt()[:crtCum]...)
+		} else {
+			fds = append(fds, crt()...)
+		}
+	}
 	vfd := &chutils.FieldDef{
 		Name:        "qa",
 		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
@@ -163,7 +211,55 @@ func xtraFields(excl bool) []*chutils.FieldDef {
 		Legal:       chutils.NewLegalValues(),
 		Missing:     "X",
 	}
-	fds = append(fds, ffd, dqfd, vintfd, pvfd, stdfd, vfd)
+	agencyfd := &chutils.FieldDef{
+		Name:        "agency",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "agency: FNMA, FHLMC",
+		Legal:       chutils.NewLegalValues(),
+		Missing:     "!",
+	}
+	msaOrigfd := &chutils.FieldDef{
+		Name:        "msaOrig",
+		ChSpec:      chutils.ChField{Base: chutils.ChFixedString, Length: 5},
+		Description: "msa as reported, before the deprecated-msa crosswalk, missing/not in MSA=XXXXX",
+		Legal:       chutils.NewLegalValues(),
+		Missing:     "XXXXX",
+	}
+	// hpiVal, eLtv, sato and incentive are optional: if the HPI or rates aren't loaded, or don't cover the row, the
+	// field takes its default, which doesn't count as a QA failure.
+	hpiValfd := &chutils.FieldDef{
+		Name:        "hpiVal",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "property value: propVal indexed from fpDt by the HPI of msa (state if msa is not in the HPI), missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: float32(0.0), HighLimit: float32(50000000.0)},
+		Missing:     float32(-1.0),
+		Default:     float32(-1.0),
+	}
+	eLtvfd := &chutils.FieldDef{
+		Name:        "eLtv",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "mark-to-market LTV: 100 * upb / hpiVal, missing=-1",
+		Legal:       &chutils.LegalValues{LowLimit: float32(0.0), HighLimit: float32(999.0)},
+		Missing:     float32(-1.0),
+		Default:     float32(-1.0),
+	}
+	satofd := &chutils.FieldDef{
+		Name:        "sato",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "spread at origination: rate - market rate at origDt, missing=" + fmt.Sprintf("%v", spreadMiss),
+		Legal:       &chutils.LegalValues{LowLimit: float32(-20.0), HighLimit: float32(20.0)},
+		Missing:     spreadMiss,
+		Default:     spreadMiss,
+	}
+	incentivefd := &chutils.FieldDef{
+		Name:        "incentive",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "refinance incentive: curRate - market rate at month, missing=" + fmt.Sprintf("%v", spreadMiss),
+		Legal:       &chutils.LegalValues{LowLimit: float32(-20.0), HighLimit: float32(20.0)},
+		Missing:     spreadMiss,
+		Default:     spreadMiss,
+	}
+	fds = append(fds, ffd, dqfd, vintfd, pvfd, stdfd, agencyfd, msaOrigfd, hpiValfd, eLtvfd, satofd, incentivefd, vfd)
 	return fds
 }
 
@@ -177,35 +273,9 @@ func vField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validat
 		vx := v
 		// The data contains some deprecated MSAs -- update these
 		if name == "msa" {
-			switch data[ind].(string) {
-			case "31100":
-				data[ind], vx = "31080", chutils.VPass
-			case "29140":
-				data[ind], vx = "29200", chutils.VPass
-			case "26100":
-				data[ind], vx = "24340", chutils.VPass
-			case "37380":
-				data[ind], vx = "19660", chutils.VPass
-			case "37700":
-				data[ind], vx = "25060", chutils.VPass
-			case "44600":
-				data[ind], vx = "48540", chutils.VPass
-			case "14060":
-				data[ind], vx = "14010", chutils.VPass
-			case "42060":
-				data[ind], vx = "42020", chutils.VPass
-			case "21940":
-				data[ind], vx = "41980", chutils.VPass
-			case "11340":
-				data[ind], vx = "24860", chutils.VPass
-			case "26180":
-				data[ind], vx = "25900", chutils.VPass
-			case "19380":
-				data[ind], vx = "19430", chutils.VPass
-			case "11300":
-				data[ind], vx = "26900", chutils.VPass
-			case "39140":
-				data[ind], vx = "39150", chutils.VPass
+			if newMsa, ok := MsaCrosswalk.Map[data[ind].(string)]; ok {
+				data[ind], vx = newMsa, chutils.VPass
+				atomic.AddInt64(&remapped, 1)
 			}
 		}
 
@@ -214,6 +284,8 @@ func vField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validat
 
 		}
 	}
+	// cross-field rules
+	res = append(res, xfFail(td, data, valid)...)
 	if len(res) > 1 {
 		return string(res), nil
 	}
@@ -228,7 +300,15 @@ func fField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validat
 	return fileName, nil
 }
 
-// fField returns the name of the file we're loading
+// agency is global since used as a closure to agencyField
+var agency string
+
+// agencyField returns the agency of the loans we're loading
+func agencyField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return agency, nil
+}
+
+// stdField returns Y if the file is standard loans
 func stdField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
 	val := "Y"
 	if Excl {
@@ -310,6 +390,95 @@ func negAmField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, val
 	return "N", nil
 }
 
+// refPool -- is none for loans not in CRT files.  This func is used to populate the field when reading other files.
+func refPoolField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return "none", nil
+}
+
+// dealName -- is none for loans not in CRT files.  This func is used to populate the field when reading other files.
+func dealNameField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return "none", nil
+}
+
+// modLossCum -- is missing for loans not in CRT files.  This func is used to populate the field when reading other files.
+func modLossCumField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return float32(-1.0), nil
+}
+
+// ceLossCum -- is missing for loans not in CRT files.  This func is used to populate the field when reading other files.
+func ceLossCumField(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, validate bool) (interface{}, error) {
+	return float32(-1.0), nil
+}
+
+// crtPos are the columns of the sf2020 layout that hold the crt() fields in CAS/CIRT files
+var crtPos = []int{0, 103, 75, 77}
+
+// crtCum is the index in crt() of the first cumulative loss field.  These fields are also in standard files, so are
+// loaded from them if Full.
+const crtCum = 2
+
+// buildCRT builds the TableDef for CAS/CIRT files.  These have the sf2020 layout with the reference pool, deal and
+// loss-sharing fields populated.
+func buildCRT() *chutils.TableDef {
+	td := build(false)
+	for ind, fd := range crt() {
+		td.FieldDefs[crtPos[ind]] = fd
+	}
+	return td
+}
+
+// crt defines the FieldDefs for the fields that are populated in the CAS and CIRT credit risk transfer files
+func crt() []*chutils.FieldDef {
+	var (
+		refPoolMiss, refPoolDef   = "unknown", "none"
+		dealNameMiss, dealNameDef = "unknown", "none"
+
+		modLossCumMin, modLossCumMax, modLossCumMiss, modLossCumDef = float32(-2000000.0), float32(2000000.0), float32(-1.0), float32(0.0)
+		ceLossCumMin, ceLossCumMax, ceLossCumMiss, ceLossCumDef     = float32(-2000000.0), float32(2000000.0), float32(-1.0), float32(0.0)
+	)
+	fds := make([]*chutils.FieldDef, 0)
+	fd := &chutils.FieldDef{
+		Name:        "refPool",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "CRT reference pool ID, none=not in CRT file, missing=" + refPoolMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     refPoolMiss,
+		Default:     refPoolDef,
+	}
+	fds = append(fds, fd)
+
+	fd = &chutils.FieldDef{
+		Name:        "dealName",
+		ChSpec:      chutils.ChField{Base: chutils.ChString, Funcs: chutils.OuterFuncs{chutils.OuterLowCardinality}},
+		Description: "CRT deal name, none=not in CRT file, missing=" + dealNameMiss,
+		Legal:       &chutils.LegalValues{},
+		Missing:     dealNameMiss,
+		Default:     dealNameDef,
+	}
+	fds = append(fds, fd)
+
+	fd = &chutils.FieldDef{
+		Name:        "modLossCum",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "CRT cumulative modification loss, missing=" + fmt.Sprintf("%v", modLossCumMiss),
+		Legal:       &chutils.LegalValues{LowLimit: modLossCumMin, HighLimit: modLossCumMax},
+		Missing:     modLossCumMiss,
+		Default:     modLossCumDef,
+	}
+	fds = append(fds, fd)
+
+	fd = &chutils.FieldDef{
+		Name:        "ceLossCum",
+		ChSpec:      chutils.ChField{Base: chutils.ChFloat, Length: 32},
+		Description: "CRT cumulative credit event net gain (+)/loss (-), missing=" + fmt.Sprintf("%v", ceLossCumMiss),
+		Legal:       &chutils.LegalValues{LowLimit: ceLossCumMin, HighLimit: ceLossCumMax},
+		Missing:     ceLossCumMiss,
+		Default:     ceLossCumDef,
+	}
+	fds = append(fds, fd)
+	return fds
+}
+
 // excluded defines the FieldDefs for the extra fields that are in the files of excluded loans
 func excluded() []*chutils.FieldDef {
 	var (
@@ -434,23 +603,7 @@ func build(excl bool) *chutils.TableDef {
 			"TX", "UT", "VA", "VI", "VT", "WA", "WI", "WV", "WY"}
 
 		msaMiss, msaDef = "XXXXX", "00000"
-		//┌─old_msa─┬─new_msa─┐
-		//│ 31100   │ 31080   │
-		//│ 29140   │ 29200   │
-		//│ 26100   │ 24340   │
-		//│ 37380   │ 19660   │
-		//│ 37700   │ 25060   │
-		//│ 44600   │ 48540   │
-		//│ 14060   │ 14010   │
-		//│ 42060   │ 42020   │
-		//│ 21940   │ 41980   │
-		//│ 11340   │ 24860   │
-		//│ 26180   │ 25900   │
-		//│ 19380   │ 19430   │
-		//│ 11300   │ 26900   │
-		//│ 39140   │ 39150   │
-		msaLvl = []string{
-			"31100", "29140", "26100", "37380", "37700", "44600", "14060", "42060", "21940", "11340", "26180", "19380", "11300", "39140",
+		msaLvl          = []string{
 			"49620", "49660", "49420", "49740", "49700", "49500",
 
 			msaDef, "10100", "10140", "10180", "10220", "10300", "10380", "10420", "10460", "10500",
@@ -712,6 +865,10 @@ func build(excl bool) *chutils.TableDef {
 		totDefrlMin, totDefrlMax, totDefrlMiss, totDefrlDef     = float32(0.0), float32(200000.0), float32(-1.0), float32(0.0)
 	)
 
+	// the crosswalk codes are legal: vField maps the deprecated codes to the current codes, which may be from a
+	// delineation newer than msaLvl
+	msaLvl = append(msaLvl, MsaCrosswalk.codes()...)
+
 	// field prepends a 0 for values under 10
 	for dq := 0; dq <= 99; dq++ {
 		if dq < 10 {
@@ -1788,6 +1945,16 @@ func build(excl bool) *chutils.TableDef {
 	}
 	fds[107] = fd
 
+	// load the columns the lean schema drops
+	if Full {
+		for ind, fdf := range full() {
+			fds[ind] = fdf
+		}
+		for ind, fdc := range crt()[crtCum:] {
+			fds[crtPos[crtCum+ind]] = fdc
+		}
+	}
+
 	// if we're reading a file of excluded loans, add in the extra fields in those files.
 	if excl {
 		for ind, fdx := range excluded() {
@@ -1805,16 +1972,46 @@ func build(excl bool) *chutils.TableDef {
 	//   stepMod
 	//   payPl
 	//   accrInt
-	//   eLtv
 	//   dqDis
 	//   modCLoss
 
 	return chutils.NewTableDef("lnId, month", chutils.MergeTree, fds)
 }
 
+// CopySpec copies the description, FixedString length and LowCardinality of the field of the same name in TableDef
+// to fd.  It is a noop if fd is not in TableDef.  Tables built from the loan table use this to describe their fields.
+func CopySpec(fd *chutils.FieldDef) {
+	CopySpecFrom(TableDef, fd)
+}
+
+// CopySpecFrom is CopySpec for the fields of td.
+func CopySpecFrom(td *chutils.TableDef, fd *chutils.FieldDef) {
+	_, fraw, err := td.Get(fd.Name)
+	if err != nil {
+		return
+	}
+	fd.Description = fraw.Description
+	if fraw.ChSpec.Base == chutils.ChFixedString {
+		fd.ChSpec.Base = chutils.ChFixedString
+		fd.ChSpec.Length = fraw.ChSpec.Length
+	}
+	for _, fnc := range fraw.ChSpec.Funcs {
+		if fnc == chutils.OuterLowCardinality {
+			fd.ChSpec.Funcs = append(fd.ChSpec.Funcs, fnc)
+		}
+	}
+}
+
+// CreateHarpMap creates an empty map of non-HARP loans to HARP loans if table does not exist.
+func CreateHarpMap(table string, con *chutils.Connect) error {
+	qry := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (oldLnId String, harpLnId String) ENGINE=MergeTree() ORDER BY oldLnId", table)
+	_, err := con.Exec(qry)
+	return err
+}
+
 // LoadHarpMap loads the mapping of non-HARP loans that refinanced into HARP loans.
 func LoadHarpMap(sourceFile string, table string, con *chutils.Connect) (err error) {
-	f, err := os.Open(sourceFile)
+	f, err := Open(sourceFile)
 	if err != nil {
 		return err
 	}
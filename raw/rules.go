@@ -0,0 +1,97 @@
+package raw
+
+import (
+	"github.com/invertedv/chutils"
+	"time"
+)
+
+// Rule is a cross-field consistency check evaluated on each row.  The failures are added to the qa field as
+// xf:<Name>.
+type Rule struct {
+	Name string                                                                 // Name is the name of the rule
+	Fail func(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool // Fail returns true if the row fails the rule
+}
+
+// Rules are the cross-field rules evaluated by the load.  Add rules with AddRule.
+var Rules = []*Rule{
+	{Name: "cltvLtLtv", Fail: cltvLtLtv},
+	{Name: "fpDtLtOrigDt", Fail: fpDtLtOrigDt},
+	{Name: "matDtTerm", Fail: matDtTerm},
+	{Name: "coFicoOneBorr", Fail: coFicoOneBorr},
+	{Name: "zbDtNoZb", Fail: zbDtNoZb},
+}
+
+// AddRule adds a cross-field rule to Rules.
+func AddRule(r *Rule) {
+	Rules = append(Rules, r)
+}
+
+// xfFail returns the qa entries of the rules the row fails
+func xfFail(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) []byte {
+	res := make([]byte, 0)
+	for _, r := range Rules {
+		if r.Fail(td, data, valid) {
+			res = append(res, []byte("xf:"+r.Name+":")...)
+		}
+	}
+	return res
+}
+
+// value returns the value of field name and true if it passed validation.
+func value(td *chutils.TableDef, data chutils.Row, valid chutils.Valid, name string) (interface{}, bool) {
+	ind, _, err := td.Get(name)
+	if err != nil || valid[ind] != chutils.VPass {
+		return nil, false
+	}
+	return data[ind], true
+}
+
+// cltvLtLtv fails if cltv < ltv
+func cltvLtLtv(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool {
+	ltv, ok1 := value(td, data, valid, "ltv")
+	cltv, ok2 := value(td, data, valid, "cltv")
+	return ok1 && ok2 && cltv.(int32) < ltv.(int32)
+}
+
+// fpDtLtOrigDt fails if fpDt is before origDt
+func fpDtLtOrigDt(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool {
+	origDt, ok1 := value(td, data, valid, "origDt")
+	fpDt, ok2 := value(td, data, valid, "fpDt")
+	return ok1 && ok2 && fpDt.(time.Time).Before(origDt.(time.Time))
+}
+
+// matDtTerm fails if matDt is more than a month from fpDt + term - 1 months.  Modified loans are not checked since
+// the modification may change matDt.
+func matDtTerm(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool {
+	fpDt, ok1 := value(td, data, valid, "fpDt")
+	matDt, ok2 := value(td, data, valid, "matDt")
+	term, ok3 := value(td, data, valid, "term")
+	mod, ok4 := value(td, data, valid, "mod")
+	if !ok1 || !ok2 || !ok3 || !ok4 || mod.(string) != "N" {
+		return false
+	}
+	months := func(dt time.Time) int { return 12*dt.Year() + int(dt.Month()) }
+	diff := months(matDt.(time.Time)) - months(fpDt.(time.Time)) - int(term.(int32)) + 1
+	return diff > 1 || diff < -1
+}
+
+// coFicoOneBorr fails if there is a co-borrower fico and only one borrower
+func coFicoOneBorr(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool {
+	numBorr, ok1 := value(td, data, valid, "numBorr")
+	_, ok2 := value(td, data, valid, "coFico")
+	return ok1 && ok2 && numBorr.(int32) == 1
+}
+
+// zbDtNoZb fails if zbDt is populated and there is no zero balance code
+func zbDtNoZb(td *chutils.TableDef, data chutils.Row, valid chutils.Valid) bool {
+	_, ok := value(td, data, valid, "zbDt")
+	if !ok {
+		return false
+	}
+	ind, _, err := td.Get("zb")
+	if err != nil {
+		return false
+	}
+	zb := data[ind].(string)
+	return zb == "00" || zb == ""
+}
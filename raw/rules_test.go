@@ -0,0 +1,85 @@
+package raw
+
+import (
+	"github.com/invertedv/chutils"
+	"testing"
+	"time"
+)
+
+// testRow returns a row of td with the values vals, which pass validation.  The other fields fail validation.
+func testRow(t *testing.T, td *chutils.TableDef, vals map[string]interface{}) (chutils.Row, chutils.Valid) {
+	data, valid := make(chutils.Row, len(td.FieldDefs)), make(chutils.Valid, len(td.FieldDefs))
+	for ind := range valid {
+		valid[ind] = chutils.VValueFail
+	}
+	for name, val := range vals {
+		ind, _, err := td.Get(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data[ind], valid[ind] = val, chutils.VPass
+	}
+	return data, valid
+}
+
+func TestRules(t *testing.T) {
+	dt := func(yr int, mon time.Month) time.Time { return time.Date(yr, mon, 1, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		rule string
+		vals map[string]interface{}
+		want bool
+	}{
+		{"cltvLtLtv", map[string]interface{}{"ltv": int32(80), "cltv": int32(70)}, true},
+		{"cltvLtLtv", map[string]interface{}{"ltv": int32(80), "cltv": int32(80)}, false},
+		{"cltvLtLtv", map[string]interface{}{"ltv": int32(80)}, false},
+
+		{"fpDtLtOrigDt", map[string]interface{}{"origDt": dt(2020, 3), "fpDt": dt(2020, 2)}, true},
+		{"fpDtLtOrigDt", map[string]interface{}{"origDt": dt(2020, 3), "fpDt": dt(2020, 5)}, false},
+		{"fpDtLtOrigDt", map[string]interface{}{"fpDt": dt(2020, 2)}, false},
+
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2050, 4), "term": int32(360), "mod": "N"}, false},
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2050, 5), "term": int32(360), "mod": "N"}, false},
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2050, 6), "term": int32(360), "mod": "N"}, true},
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2040, 4), "term": int32(360), "mod": "N"}, true},
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2040, 4), "term": int32(360), "mod": "Y"}, false},
+		{"matDtTerm", map[string]interface{}{"fpDt": dt(2020, 5), "matDt": dt(2040, 4), "term": int32(360)}, false},
+
+		{"coFicoOneBorr", map[string]interface{}{"numBorr": int32(1), "coFico": int32(700)}, true},
+		{"coFicoOneBorr", map[string]interface{}{"numBorr": int32(2), "coFico": int32(700)}, false},
+		{"coFicoOneBorr", map[string]interface{}{"numBorr": int32(1)}, false},
+
+		{"zbDtNoZb", map[string]interface{}{"zbDt": dt(2021, 1), "zb": "00"}, true},
+		{"zbDtNoZb", map[string]interface{}{"zbDt": dt(2021, 1), "zb": ""}, true},
+		{"zbDtNoZb", map[string]interface{}{"zbDt": dt(2021, 1), "zb": "01"}, false},
+		{"zbDtNoZb", map[string]interface{}{"zb": "00"}, false},
+	}
+	td := build(false)
+	for _, tt := range tests {
+		var rule *Rule
+		for _, r := range Rules {
+			if r.Name == tt.rule {
+				rule = r
+			}
+		}
+		if rule == nil {
+			t.Fatalf("no rule %s", tt.rule)
+		}
+		data, valid := testRow(t, td, tt.vals)
+		// zbDtNoZb reads zb whether or not it passed
+		if ind, _, _ := td.Get("zb"); data[ind] == nil {
+			data[ind] = ""
+		}
+		if got := rule.Fail(td, data, valid); got != tt.want {
+			t.Errorf("%s(%v) = %v, want %v", tt.rule, tt.vals, got, tt.want)
+		}
+	}
+}
+
+func TestXfFail(t *testing.T) {
+	td := build(false)
+	data, valid := testRow(t, td, map[string]interface{}{"ltv": int32(80), "cltv": int32(70), "numBorr": int32(1),
+		"coFico": int32(700), "zb": "01"})
+	if got, want := string(xfFail(td, data, valid)), "xf:cltvLtLtv:xf:coFicoOneBorr:"; got != want {
+		t.Errorf("xfFail = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,135 @@
+package raw
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"github.com/invertedv/chutils/file"
+	"sync"
+)
+
+// maxSamples is the maximum # of distinct bad values kept per field
+const maxSamples = 5
+
+// FieldSummary summarizes the validation of a field over a source file.
+type FieldSummary struct {
+	Field     string   // Field is the name of the field
+	Rows      int64    // Rows is the # of rows checked
+	Failed    int64    // Failed is the # of rows that failed validation
+	Defaulted int64    // Defaulted is the # of rows set to the default value
+	Samples   []string // Samples are up to 5 distinct values that failed validation
+	drop      bool     // drop is true if the field is not loaded
+}
+
+// tally accumulates the FieldSummary of each field across the concurrent readers
+type tally struct {
+	sync.Mutex
+	fields []*FieldSummary
+}
+
+// summary is the tally of the current load
+var summary = &tally{}
+
+// Summaries returns the validation summary of each field loaded by the last load.
+func Summaries() []*FieldSummary {
+	summary.Lock()
+	defer summary.Unlock()
+	fss := make([]*FieldSummary, 0)
+	for _, fs := range summary.fields {
+		if !fs.drop {
+			fss = append(fss, fs)
+		}
+	}
+	return fss
+}
+
+// reset starts a new tally for the fields of td
+func (t *tally) reset(td *chutils.TableDef) {
+	t.Lock()
+	defer t.Unlock()
+	t.fields = make([]*FieldSummary, 0)
+	for ind := 0; ind < len(td.FieldDefs); ind++ {
+		fd := td.FieldDefs[ind]
+		t.fields = append(t.fields, &FieldSummary{Field: fd.Name, Samples: make([]string, 0), drop: fd.Drop})
+	}
+}
+
+// add adds a batch of rows to the tally.  raw are the values before validation.
+func (t *tally) add(td *chutils.TableDef, raw []chutils.Row, valid []chutils.Valid) {
+	t.Lock()
+	defer t.Unlock()
+	for r, row := range valid {
+		for ind, v := range row {
+			fs := t.fields[ind]
+			fs.Rows++
+			switch v {
+			case chutils.VPass:
+			case chutils.VDefault:
+				fs.Defaulted++
+			default:
+				fs.Failed++
+				fs.addSample(fmt.Sprintf("%v", raw[r][ind]))
+			}
+		}
+	}
+}
+
+// addSample adds val to the samples if it's new and there's room
+func (fs *FieldSummary) addSample(val string) {
+	if len(fs.Samples) >= maxSamples {
+		return
+	}
+	for _, s := range fs.Samples {
+		if s == val {
+			return
+		}
+	}
+	fs.Samples = append(fs.Samples, val)
+}
+
+// tallyReader validates the rows read from the underlying reader and adds the results to summary.  The values
+// before validation are needed for the samples, so the underlying reader does not validate.
+// Rows that fail a Fatal field are sent to quarantine rather than returned.
+type tallyReader struct {
+	chutils.Input
+}
+
+func (r *tallyReader) Read(nTarget int, validate bool) (data []chutils.Row, valid []chutils.Valid, err error) {
+	if !validate {
+		return r.Input.Read(nTarget, validate)
+	}
+	td := r.TableSpec()
+	fatal, err := fatalInds(td, Fatal)
+	if err != nil {
+		return nil, nil, err
+	}
+	// keep reading until we have a row to return, since an empty read signals the end of the data
+	for len(data) == 0 && err == nil {
+		var rows []chutils.Row
+		line := 0
+		if fr, ok := r.Input.(*file.Reader); ok {
+			line = fr.RowsRead
+		}
+		rows, _, err = r.Input.Read(nTarget, false)
+		raw := make([]chutils.Row, 0, len(rows))
+		vrows := make([]chutils.Valid, 0, len(rows))
+		for ind, row := range rows {
+			rawRow := make(chutils.Row, len(row))
+			copy(rawRow, row)
+			raw = append(raw, rawRow)
+			vrow := make(chutils.Valid, len(row))
+			for col := range row {
+				row[col], vrow[col] = td.FieldDefs[col].Validator(row[col])
+			}
+			vrows = append(vrows, vrow)
+
+			if failed := fatalFail(td, fatal, rawRow, vrow); len(failed) > 0 {
+				quarantine.add(rawRow, line+ind+1, failed)
+				continue
+			}
+			data = append(data, row)
+			valid = append(valid, vrow)
+		}
+		summary.add(td, raw, vrows)
+	}
+	return data, valid, err
+}
@@ -0,0 +1,202 @@
+// Package rollrate builds month-over-month delinquency transition matrices from the table built by package collapse.
+//
+// The state of a loan each month is one of States:
+//   - C.       current.
+//   - 30, 60, 90.  1, 2 and 3 months delinquent.
+//   - 120+.    4 or more months delinquent.
+//   - FCL.     on or after fclDt and not zero balance.
+//   - REO.     zero balance from an REO disposition (09).
+//   - prepaid. zero balance from prepayment (01).
+//   - other.   any other zero balance code, including a third party sale (02), which never goes to REO.
+//
+// The table has one row per segment per calendar month per (fromState, toState) with the transition count and the
+// probability of toState given fromState.  Months with a missing dq are skipped.
+package rollrate
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/invertedv/chutils"
+	s "github.com/invertedv/chutils/sql"
+	"github.com/invertedv/fannie/raw"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// States are the loan states, in the order of the columns of the CSV matrices
+var States = []string{"C", "30", "60", "90", "120+", "FCL", "REO", "prepaid", "other"}
+
+// ByFields are the fields the transitions may be segmented by
+var ByFields = []string{"vintage", "state", "channel", "standard"}
+
+// Build builds table from sourceTable, the table built by package collapse.  by are fields from ByFields to
+// segment by.
+func Build(sourceTable string, table string, by []string, con *chutils.Connect) error {
+	if e := checkBy(by); e != nil {
+		return e
+	}
+	seg := strings.Join(append(by, "month"), ", ")
+	q := strings.Replace(strings.Replace(qry, "sourceTable", sourceTable, 1), "<seg>", seg, -1)
+
+	rdr := s.NewReader(q, con)
+	defer func() { _ = rdr.Close() }()
+	if e := rdr.Init(seg+", fromState, toState", chutils.MergeTree); e != nil {
+		return e
+	}
+	for _, f := range rdr.TableSpec().FieldDefs {
+		if f.Name != "month" {
+			raw.CopySpec(f)
+		}
+		switch f.Name {
+		case "month":
+			f.Description = "month of toState"
+		case "fromState":
+			f.Description = "state the prior month: " + strings.Join(States, ", ")
+			f.ChSpec.Funcs = append(f.ChSpec.Funcs, chutils.OuterLowCardinality)
+		case "toState":
+			f.Description = "state this month: " + strings.Join(States, ", ")
+			f.ChSpec.Funcs = append(f.ChSpec.Funcs, chutils.OuterLowCardinality)
+		case "n":
+			f.Description = "# of loans moving from fromState to toState"
+		case "prob":
+			f.Description = "probability of toState given fromState"
+		}
+	}
+	if e := rdr.TableSpec().Create(con, table); e != nil {
+		return e
+	}
+	rdr.Name = table
+	rdr.Sql = strings.Replace(rdr.Sql, "LIMIT 10", "", 1)
+	return rdr.Insert()
+}
+
+// WriteCSV writes the transitions in table, built by Build with the same by, to w.  There is a row per segment,
+// month and fromState with a count column (n:<state>) and a probability column (p:<state>) for each of States.
+func WriteCSV(w io.Writer, table string, by []string, con *chutils.Connect) error {
+	if e := checkBy(by); e != nil {
+		return e
+	}
+	cols := make([]string, 0)
+	for _, b := range by {
+		cols = append(cols, fmt.Sprintf("toString(%s)", b))
+	}
+	cols = append(cols, "toString(month)", "fromState", "toState", "n", "prob")
+	qry := fmt.Sprintf("SELECT %s FROM %s ORDER BY %s", strings.Join(cols, ", "), table,
+		strings.Join(append(by, "month", "fromState"), ", "))
+	rows, err := con.Query(qry)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	cw := csv.NewWriter(w)
+	header := append(append([]string{}, by...), "month", "fromState")
+	for _, st := range States {
+		header = append(header, "n:"+st)
+	}
+	for _, st := range States {
+		header = append(header, "p:"+st)
+	}
+	if e := cw.Write(header); e != nil {
+		return e
+	}
+
+	nKey := len(by) + 2
+	var (
+		key  []string // key is the segment, month and fromState of the current row
+		line []string // line is the CSV row being built
+	)
+	for rows.Next() {
+		vals := make([]string, nKey+1)
+		var (
+			n    int64
+			prob float64
+		)
+		dest := make([]interface{}, 0, nKey+3)
+		for ind := range vals {
+			dest = append(dest, &vals[ind])
+		}
+		dest = append(dest, &n, &prob)
+		if e := rows.Scan(dest...); e != nil {
+			return e
+		}
+		if line == nil || strings.Join(key, "|") != strings.Join(vals[:nKey], "|") {
+			if line != nil {
+				if e := cw.Write(line); e != nil {
+					return e
+				}
+			}
+			key = vals[:nKey]
+			line = append([]string{}, key...)
+			for range States {
+				line = append(line, "0", "0")
+			}
+		}
+		for ind, st := range States {
+			if st == vals[nKey] {
+				line[nKey+ind] = strconv.FormatInt(n, 10)
+				line[nKey+len(States)+ind] = strconv.FormatFloat(prob, 'f', 6, 64)
+			}
+		}
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	if line != nil {
+		if e := cw.Write(line); e != nil {
+			return e
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// checkBy checks that by are in ByFields
+func checkBy(by []string) error {
+	for _, b := range by {
+		ok := false
+		for _, f := range ByFields {
+			ok = ok || b == f
+		}
+		if !ok {
+			return fmt.Errorf("cannot segment roll rates by %s, fields are: %s", b, strings.Join(ByFields, ", "))
+		}
+	}
+	return nil
+}
+
+// qry builds the transitions.  There are placeholders for the source table and the segment fields <seg>, which
+// end with month.
+//
+// Note: the "LIMIT 10" makes the query run much faster for the Init() method.
+const qry = `
+SELECT
+  <seg>,
+  fromState,
+  toState,
+  toInt64(count(*)) AS n,
+  toFloat64(n / sum(n) OVER (PARTITION BY <seg>, fromState)) AS prob
+FROM (
+  SELECT
+    *,
+    arrayMap((d, z, m) -> multiIf(
+      z = '01', 'prepaid',
+      z = '09', 'REO',
+      z != '00' AND z != '', 'other',
+      year(fclDt) > 1970 AND m >= fclDt, 'FCL',
+      d < 0, 'missing',
+      d >= 4, '120+',
+      d = 3, '90',
+      d = 2, '60',
+      d = 1, '30',
+      'C'), monthly.dq, monthly.zb, monthly.month) AS st
+  FROM sourceTable
+  LIMIT 10)
+ARRAY JOIN
+  arrayPopFront(monthly.month) AS month,
+  arrayPopBack(st) AS fromState,
+  arrayPopFront(st) AS toState
+WHERE fromState != 'missing' AND toState != 'missing'
+GROUP BY <seg>, fromState, toState
+`
@@ -0,0 +1,113 @@
+// Package sample builds reproducible stratified samples of the table built by package collapse.
+//
+// Samples are built from the bucket field.  The buckets are rotated by Seed and each loan is assigned to train,
+// validation or test by its rotated bucket, so the split fractions are multiples of 1/(# of buckets) -- see the
+// -buckets flag of fannie.  Within each stratum and split, loans are ordered by rotated bucket (ties are broken by a
+// hash of lnId) and the first Rate of them are taken, so each stratum is sampled at Rate and the same Seed gives the
+// same sample.  The sample table has the structure of the source table plus the field split.
+package sample
+
+import (
+	"fmt"
+	"github.com/invertedv/chutils"
+	"math"
+	"strings"
+)
+
+// Sample specifies a stratified sample
+type Sample struct {
+	Strata     []string // Strata are the fields to stratify by. If empty, the sample is not stratified.
+	Rate       float64  // Rate is the fraction of each stratum to sample
+	Train      float64  // Train is the fraction of the buckets assigned to train
+	Validation float64  // Validation is the fraction of the buckets assigned to validation. The rest is test.
+	Seed       uint64   // Seed rotates the buckets
+}
+
+// DefaultStrata are the default fields to stratify by
+var DefaultStrata = []string{"vintage", "state", "standard"}
+
+// Build builds table as the sample smp of sourceTable, the table built by package collapse.
+func Build(sourceTable string, table string, smp *Sample, con *chutils.Connect) error {
+	if smp.Rate <= 0 || smp.Rate > 1 {
+		return fmt.Errorf("sample rate must be in (0, 1], got %v", smp.Rate)
+	}
+	if smp.Train < 0 || smp.Validation < 0 || smp.Train+smp.Validation > 1 {
+		return fmt.Errorf("%s", "train and validation fractions must be non-negative and sum to at most 1")
+	}
+	if e := checkStrata(sourceTable, smp.Strata, con); e != nil {
+		return e
+	}
+	var buckets uint64
+	if e := con.QueryRow(fmt.Sprintf("SELECT toUInt64(max(bucket) + 1) FROM %s", sourceTable)).Scan(&buckets); e != nil {
+		return e
+	}
+
+	qrys := []string{
+		fmt.Sprintf("DROP TABLE IF EXISTS %s", table),
+		fmt.Sprintf("CREATE TABLE %s AS %s", table, sourceTable),
+		fmt.Sprintf("ALTER TABLE %s ADD COLUMN split LowCardinality(String) COMMENT 'model set: train, validation, test'",
+			table),
+	}
+	for _, qry := range qrys {
+		if _, e := con.Exec(qry); e != nil {
+			return e
+		}
+	}
+
+	partition := strings.Join(append(append([]string{}, smp.Strata...), "split"), ", ")
+	// the split is by rotated bucket: [0, nTrain) is train, [nTrain, nValidation) is validation
+	nTrain := uint64(math.Round(smp.Train * float64(buckets)))
+	nValidation := uint64(math.Round((smp.Train + smp.Validation) * float64(buckets)))
+	q := strings.NewReplacer("sourceTable", sourceTable, "sampleTable", table, "<partition>", partition,
+		"<seed>", fmt.Sprintf("%d", smp.Seed), "<rotate>", fmt.Sprintf("%d", smp.Seed%buckets),
+		"<buckets>", fmt.Sprintf("%d", buckets), "<rate>", fmt.Sprintf("%v", smp.Rate),
+		"<nTrain>", fmt.Sprintf("%d", nTrain), "<nValidation>", fmt.Sprintf("%d", nValidation)).Replace(insertQry)
+	_, err := con.Exec(q)
+	return err
+}
+
+// checkStrata checks that strata are fields of table that aren't arrays
+func checkStrata(table string, strata []string, con *chutils.Connect) error {
+	rows, err := con.Query(fmt.Sprintf("DESCRIBE %s", table))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+	fields := make(map[string]bool)
+	for rows.Next() {
+		var name, chType, defaultType, defaultExpression, comment, codec, ttl string
+		if e := rows.Scan(&name, &chType, &defaultType, &defaultExpression, &comment, &codec, &ttl); e != nil {
+			return e
+		}
+		fields[name] = !strings.HasPrefix(chType, "Array(")
+	}
+	if e := rows.Err(); e != nil {
+		return e
+	}
+	for _, s := range strata {
+		if !fields[s] {
+			return fmt.Errorf("cannot stratify by %s: not a field of %s", s, table)
+		}
+	}
+	return nil
+}
+
+// insertQry inserts the sample.  There are placeholders for the tables (sourceTable, sampleTable), the window
+// partition, the seed, the bucket rotation, the # of buckets, the rate and the bucket limits of train and validation.
+const insertQry = `
+INSERT INTO sampleTable
+SELECT
+  * EXCEPT (smpBucket, smpRow, smpCnt)
+FROM (
+  SELECT
+    *,
+    row_number() OVER (PARTITION BY <partition> ORDER BY smpBucket, cityHash64(lnId, <seed>)) AS smpRow,
+    count(*) OVER (PARTITION BY <partition>) AS smpCnt
+  FROM (
+    SELECT
+      *,
+      (toUInt64(bucket) + <rotate>) % <buckets> AS smpBucket,
+      multiIf(smpBucket < <nTrain>, 'train', smpBucket < <nValidation>, 'validation', 'test') AS split
+    FROM sourceTable))
+WHERE smpRow <= ceil(<rate> * smpCnt)
+`